@@ -2,15 +2,24 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/akantsevoi/test-environment/internal/clientapi"
+	"github.com/akantsevoi/test-environment/internal/gatewayapi"
 	"github.com/akantsevoi/test-environment/internal/maroon"
 	"github.com/akantsevoi/test-environment/internal/p2p"
 	"github.com/akantsevoi/test-environment/pkg/election"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
 	"github.com/akantsevoi/test-environment/pkg/logger"
+	"github.com/akantsevoi/test-environment/pkg/membership"
+	"github.com/akantsevoi/test-environment/pkg/wal"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -21,12 +30,29 @@ func main() {
 	logger.Infof(logger.Application, "Using etcd endpoints: %v", vars.etcdEndpoints)
 
 	// start TCP p2p distributor
-	p2pDistr, confirmedTXsCh := p2p.New(podName, "8080")
-	p2pDistr.UpdateHosts([]string{
-		"maroon-1.maroon:8080",
-		"maroon-2.maroon:8080",
-	})
-	go p2pDistr.Start()
+	p2pOpts := []p2p.Option{
+		p2p.WithRegion(vars.region),
+		p2p.WithQueue(vars.peerQueueSize, vars.peerOverflow),
+	}
+	if vars.replicaSelector != nil {
+		p2pOpts = append(p2pOpts, p2p.WithReplicaSelector(vars.replicaSelector))
+	}
+	if vars.speculateAfter > 0 {
+		p2pOpts = append(p2pOpts, p2p.WithSpeculativeSend(vars.speculateAfter))
+	}
+	if vars.peerTLS != nil {
+		p2pOpts = append(p2pOpts, p2p.WithTLS(*vars.peerTLS))
+	}
+	if vars.quorumNodesPerRegion > 0 {
+		p2pOpts = append(p2pOpts, p2p.WithQuorum(p2p.RegionMajority(vars.quorumNodesPerRegion)))
+	}
+	p2pDistr, confirmedTXsCh := p2p.New(podName, "8080", p2pOpts...)
+	go func() {
+		// TODO: react somehow, for example don't campaign when most of the peers are unhealthy
+		for ev := range p2pDistr.PeerEvents() {
+			logger.Infof(logger.Network, "peer %v healthy: %v, state: %v, failures: %d, rtt: %v", ev.Addr, ev.Healthy, ev.ConnState, ev.Failures, ev.RTT)
+		}
+	}()
 
 	cli, err := clientv3.New(clientv3.Config{
 		Endpoints:   vars.etcdEndpoints,
@@ -38,47 +64,153 @@ func main() {
 	}
 	defer cli.Close()
 
-	// watching hashes
-	watchChan := cli.Watch(context.Background(), maroon.HashesKey+"/", clientv3.WithPrefix())
+	// peers are the other discovered nodes
+	self := membership.Member{
+		ID:           podName,
+		Addr:         vars.advertiseAddr,
+		Region:       vars.region,
+		Capabilities: []string{vars.protocol},
+	}
+	var discovery interface {
+		Run(ctx context.Context, onChange func([]membership.Member)) error
+	}
+	switch vars.discovery {
+	case discoveryDNS:
+		discovery = membership.NewDNSDiscovery(net.DefaultResolver, vars.dns, self)
+	default:
+		discovery = membership.NewRegistry(cli, maroon.MembersKey, self, 10)
+	}
+	go func() {
+		err := discovery.Run(context.Background(), func(members []membership.Member) {
+			peers := make([]p2p.Peer, 0, len(members))
+			for _, m := range members {
+				peers = append(peers, p2p.Peer{Addr: m.Addr, Region: m.Region})
+			}
+			logger.Infof(logger.Membership, "peers: %v", peers)
+			p2pDistr.UpdateHosts(peers)
+		})
+		if err != nil {
+			logger.Fatalf(logger.Membership, "peer discovery stopped: %v", err)
+		}
+	}()
 
 	// Start application logic in a separate goroutine
 	stopCh := make(chan struct{})
-	isLeaderCh := make(chan bool)
-	app := maroon.New(cli, p2pDistr)
-	go app.Run(isLeaderCh, confirmedTXsCh, watchChan, stopCh)
-	isLeaderCh <- false
+	isLeaderCh := make(chan maroon.Leadership)
+	// leader writes were fenced off, carries the revision of that term
+	var demotedCh <-chan int64
+	// where gateways submit transactions, offsets protocol only
+	var txSink gatewayapi.TxSink
 
-	leader := election.NewLeader(cli, maroon.LeaderKey, podName)
+	switch vars.protocol {
+	case protocolOffsets:
+		// replay the whole history, so every node goes through the same commit steps
+		// TODO: start from a snapshot instead
+		watchChan := cli.Watch(context.Background(), maroon.VectorKey, clientv3.WithRev(1))
 
-	// imitation of incoming requests
-	go func() {
-		tickerCh := time.Tick(10 * time.Second)
-		for tick := range tickerCh {
-			timestamp := tick.Unix()
+		app, committedCh := maroon.NewOffsetApp(cli, p2pDistr, vars.clusterSize)
+		p2pDistr.SetOffsetStore(app)
+		txSink = app
+		demotedCh = app.Demoted()
+		go p2pDistr.Start()
+		go app.Run(isLeaderCh, watchChan, stopCh)
+
+		go func() {
+			for tx := range committedCh {
+				logger.Infof(logger.Application, "committed tx (%d, %d): %d bytes", tx.Key.RangeIndex, tx.Key.Offset, len(tx.Payload))
+			}
+		}()
+	default:
+		// watching hashes
+		watchChan := cli.Watch(context.Background(), maroon.HashesKey+"/", clientv3.WithPrefix())
 
-			app.AddOp(maroon.Operation{
-				OpType: maroon.PrintTimestamp,
-				Value:  strconv.FormatInt(timestamp, 10),
-			})
+		appOpts := []maroon.Option{
+			maroon.WithSnapshotEvery(vars.snapshotEvery),
+			maroon.WithSealPolicy(vars.sealPolicy),
+			maroon.WithDedupWindow(vars.dedupWindow),
+		}
+		if vars.walDir != "" {
+			w, err := wal.Open(vars.walDir, wal.WithSync(vars.walSync))
+			if err != nil {
+				logger.Fatalf(logger.Application, "failed to open wal: %v", err)
+			}
+			defer w.Close()
+			appOpts = append(appOpts, maroon.WithWAL(w))
+		}
+
+		app := maroon.New(cli, p2pDistr, appOpts...)
+		if err := app.Recover(); err != nil {
+			logger.Fatalf(logger.Application, "failed to recover from wal: %v", err)
+		}
+		p2pDistr.SetTxStore(app)
+		p2pDistr.SetSnapshotSource(app)
+		demotedCh = app.Demoted()
+		go p2pDistr.Start()
+		go app.Run(isLeaderCh, confirmedTXsCh, watchChan, stopCh)
+
+		// operations from the clients, followers forward them to the leader
+		leaderResolver := clientapi.NewETCDLeaderResolver(cli, maroon.LeaderKey, func(nodeID string) string {
+			return fmt.Sprintf("%s.maroon:8082", nodeID)
+		})
+		clientAPI := clientapi.New("8082", app, leaderResolver)
+		go clientAPI.Start()
+
+		// imitation of incoming requests
+		go func() {
+			tickerCh := time.Tick(10 * time.Second)
+			for tick := range tickerCh {
+				timestamp := tick.Unix()
+
+				_, err := app.AddOp(maroon.Operation{
+					OpType: maroon.PrintTimestamp,
+					Value:  strconv.FormatInt(timestamp, 10),
+				})
+				if err != nil && !errors.Is(err, maroon.ErrNotLeader) {
+					logger.Errorf(logger.Application, "failed to add op: %v", err)
+				}
+			}
+		}()
+	}
+	isLeaderCh <- maroon.Leadership{}
+
+	// expvar metrics under /debug/vars
+	go func() {
+		if err := http.ListenAndServe(":8083", nil); err != nil {
+			logger.Errorf(logger.Application, "metrics server stopped: %v", err)
 		}
 	}()
 
+	// key ranges for the communication gateways
+	// gateways only work with the offsets protocol, so they can't connect to the other nodes at all
+	if txSink != nil {
+		gatewayAPI := gatewayapi.New("8081", keyrange.NewAllocator(cli, maroon.RangesKey), txSink)
+		go gatewayAPI.Start()
+	}
+
+	leader := election.NewLeader(cli, maroon.LeaderKey, podName)
+
 	for {
 		const timeBetweenAttempts = 3 * time.Second
 		leaderCh, err := leader.Campaign()
 		if err != nil {
-			isLeaderCh <- false
+			isLeaderCh <- maroon.Leadership{}
 			logger.Errorf(logger.Election, "failed to campaign: %v", err)
 			time.Sleep(timeBetweenAttempts)
 			continue
 		}
 
 		logger.Infof(logger.Election, "pod %s became leader", podName)
-		isLeaderCh <- true
+		leaderRev := leader.Revision()
+		isLeaderCh <- maroon.Leadership{IsLeader: true, Revision: leaderRev}
 
 		// Wait for leadership loss
-		<-leaderCh
-		isLeaderCh <- false
+		if waitForLeadershipLoss(leaderCh, demotedCh, leaderRev) {
+			// somebody else is the leader in etcd already
+			if err := leader.Resign(); err != nil {
+				logger.Errorf(logger.Election, "failed to resign: %v", err)
+			}
+		}
+		isLeaderCh <- maroon.Leadership{}
 		logger.Infof(logger.Election, "lost leadership")
 
 		// this wait is for followers or for the leader who lost leadership to wait and start campaign again
@@ -91,9 +223,70 @@ func main() {
 	close(isLeaderCh)
 }
 
+// true if the application was demoted in this term
+// demotions from the earlier terms are ignored
+func waitForLeadershipLoss(leaderCh <-chan struct{}, demotedCh <-chan int64, leaderRev int64) bool {
+	for {
+		select {
+		case <-leaderCh:
+			return false
+		case rev := <-demotedCh:
+			if rev == leaderRev {
+				return true
+			}
+			logger.Infof(logger.Election, "ignore demotion at revision %d, current one is %d", rev, leaderRev)
+		}
+	}
+}
+
+const (
+	// per transaction hashes and blocks, default
+	protocolBlocks = "blocks"
+	// offset vectors, see doc/communication-gateway-maroon.md
+	protocolOffsets = "offsets"
+)
+
+const (
+	// nodes register themselves in etcd, default
+	discoveryETCD = "etcd"
+	// pods of the headless service, see membership.DNSDiscovery
+	discoveryDNS = "dns"
+)
+
 type envVariables struct {
 	podName       string
 	etcdEndpoints []string
+
+	// optional
+	// host:port of the p2p service for the other nodes
+	advertiseAddr string
+	// how the peers are found
+	discovery string
+	dns       membership.DNSConfig
+	region    string
+	// 0 - default quorum policy of the transport
+	quorumNodesPerRegion int
+	protocol             string
+	// amount of maroon nodes, offsets protocol counts majority out of it
+	clusterSize int
+	// empty - state is only in memory, blocks protocol only
+	walDir  string
+	walSync wal.SyncPolicy
+	// blocks between snapshots, 0 - no snapshots
+	snapshotEvery int64
+	// when the leader seals a block, blocks protocol only
+	sealPolicy maroon.SealPolicy
+	// blocks during which client retries are recognized, 0 - no deduplication
+	dedupWindow int64
+	// not acked txs per peer and what happens when there are more
+	peerQueueSize int
+	peerOverflow  p2p.OverflowPolicy
+	// nil - peers talk without tls
+	peerTLS *p2p.TLSFiles
+	// nil - default of the transport
+	replicaSelector p2p.ReplicaSelector
+	// 0 - no speculative sends
+	speculateAfter time.Duration
 }
 
 func envs() envVariables {
@@ -108,8 +301,221 @@ func envs() envVariables {
 	}
 	endpoints := strings.Split(etcdEndpoints, ",")
 
+	var quorumNodesPerRegion int
+	if v := os.Getenv("QUORUM_NODES_PER_REGION"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatalf(logger.Application, "QUORUM_NODES_PER_REGION should be a positive number, got: %q", v)
+		}
+		quorumNodesPerRegion = n
+	}
+
+	protocol := os.Getenv("PROTOCOL")
+	switch protocol {
+	case "":
+		protocol = protocolBlocks
+	case protocolBlocks, protocolOffsets:
+	default:
+		logger.Fatalf(logger.Application, "unknown PROTOCOL: %q", protocol)
+	}
+
+	clusterSize := 3
+	if v := os.Getenv("CLUSTER_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatalf(logger.Application, "CLUSTER_SIZE should be a positive number, got: %q", v)
+		}
+		clusterSize = n
+	}
+	// no region has that many nodes, the quorum is never reached
+	if quorumNodesPerRegion > clusterSize {
+		logger.Fatalf(logger.Application, "QUORUM_NODES_PER_REGION %d is more than CLUSTER_SIZE %d", quorumNodesPerRegion, clusterSize)
+	}
+
+	walSync := wal.SyncAlways
+	switch v := os.Getenv("WAL_SYNC"); v {
+	case "", "always":
+	case "interval":
+		walSync = wal.SyncInterval
+	case "never":
+		walSync = wal.SyncNever
+	default:
+		logger.Fatalf(logger.Application, "unknown WAL_SYNC: %q", v)
+	}
+
+	snapshotEvery := int64(100)
+	if v := os.Getenv("SNAPSHOT_EVERY"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			logger.Fatalf(logger.Application, "SNAPSHOT_EVERY should be a non-negative number, got: %q", v)
+		}
+		snapshotEvery = n
+	}
+
+	sealPolicy := maroon.DefaultSealPolicy()
+	if v := os.Getenv("SEAL_MAX_OPS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Fatalf(logger.Application, "SEAL_MAX_OPS should be a non-negative number, got: %q", v)
+		}
+		sealPolicy.MaxOps = n
+	}
+	if v := os.Getenv("SEAL_MAX_BYTES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			logger.Fatalf(logger.Application, "SEAL_MAX_BYTES should be a non-negative number, got: %q", v)
+		}
+		sealPolicy.MaxBytes = n
+	}
+	if v := os.Getenv("SEAL_LINGER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Fatalf(logger.Application, "SEAL_LINGER should be a non-negative duration, got: %q", v)
+		}
+		sealPolicy.Linger = d
+	}
+	if sealPolicy == (maroon.SealPolicy{}) {
+		logger.Fatalf(logger.Application, "at least one of SEAL_MAX_OPS, SEAL_MAX_BYTES, SEAL_LINGER should be set, otherwise blocks are never sealed")
+	}
+
+	dedupWindow := int64(1000)
+	if v := os.Getenv("DEDUP_WINDOW"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			logger.Fatalf(logger.Application, "DEDUP_WINDOW should be a non-negative number, got: %q", v)
+		}
+		dedupWindow = n
+	}
+
+	peerQueueSize := p2p.DefaultQueueSize
+	if v := os.Getenv("PEER_QUEUE_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			logger.Fatalf(logger.Application, "PEER_QUEUE_SIZE should be a positive number, got: %q", v)
+		}
+		peerQueueSize = n
+	}
+
+	peerOverflow := p2p.OverflowReject
+	switch v := os.Getenv("PEER_OVERFLOW"); v {
+	case "", "reject":
+	case "block":
+		peerOverflow = p2p.OverflowBlock
+	case "drop-oldest":
+		peerOverflow = p2p.OverflowDropOldest
+	default:
+		logger.Fatalf(logger.Application, "unknown PEER_OVERFLOW: %q", v)
+	}
+
+	var peerTLS *p2p.TLSFiles
+	tlsFiles := p2p.TLSFiles{
+		CertFile: os.Getenv("PEER_TLS_CERT"),
+		KeyFile:  os.Getenv("PEER_TLS_KEY"),
+		CAFile:   os.Getenv("PEER_TLS_CA"),
+	}
+	switch {
+	case tlsFiles == p2p.TLSFiles{}:
+	case tlsFiles.CertFile == "" || tlsFiles.KeyFile == "" || tlsFiles.CAFile == "":
+		logger.Fatalf(logger.Application, "PEER_TLS_CERT, PEER_TLS_KEY and PEER_TLS_CA should be set together")
+	default:
+		peerTLS = &tlsFiles
+	}
+
+	discovery := os.Getenv("DISCOVERY")
+	switch discovery {
+	case "":
+		discovery = discoveryETCD
+	case discoveryETCD, discoveryDNS:
+	default:
+		logger.Fatalf(logger.Application, "unknown DISCOVERY: %q", discovery)
+	}
+
+	dns := membership.DNSConfig{
+		Service:  "maroon.default.svc.cluster.local",
+		PortName: "tcp",
+		Port:     8080,
+		Interval: 10 * time.Second,
+	}
+	if v := os.Getenv("DNS_SERVICE"); v != "" {
+		dns.Service = v
+	}
+	if v, ok := os.LookupEnv("DNS_PORT_NAME"); ok {
+		// empty - A records
+		dns.PortName = v
+	}
+	// downward API, A records don't have the pod name
+	dns.SelfIP = os.Getenv("POD_IP")
+	if discovery == discoveryDNS {
+		if dns.PortName == "" && dns.SelfIP == "" {
+			logger.Fatalf(logger.Application, "POD_IP is required for DISCOVERY=dns with A records")
+		}
+		// every discovered peer gets the node's region
+		if quorumNodesPerRegion > 0 {
+			logger.Fatalf(logger.Application, "DISCOVERY=dns doesn't know peer regions, it can't be used with QUORUM_NODES_PER_REGION")
+		}
+	}
+
+	region := os.Getenv("REGION")
+	if v := os.Getenv("LABELS_FILE"); region == "" && v != "" {
+		// downward API
+		l, err := membership.LabelFromFile(v, "topology.kubernetes.io/region")
+		if err != nil {
+			logger.Fatalf(logger.Application, "failed to get region from pod labels: %v", err)
+		}
+		region = l
+	}
+
+	var replicaSelector p2p.ReplicaSelector
+	switch v := os.Getenv("REPLICA_SELECTION"); {
+	case v == "":
+	case v == "all":
+		replicaSelector = p2p.SendToAll()
+	case v == "nearest":
+		replicaSelector = p2p.NearestPerRegion()
+	case strings.HasPrefix(v, "fastest:"):
+		k, err := strconv.Atoi(strings.TrimPrefix(v, "fastest:"))
+		if err != nil || k < 1 {
+			logger.Fatalf(logger.Application, "REPLICA_SELECTION fastest:K should have a positive K, got: %q", v)
+		}
+		replicaSelector = p2p.FastestK(k)
+	default:
+		logger.Fatalf(logger.Application, "unknown REPLICA_SELECTION: %q", v)
+	}
+
+	var speculateAfter time.Duration
+	if v := os.Getenv("SPECULATE_AFTER"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			logger.Fatalf(logger.Application, "SPECULATE_AFTER should be a non-negative duration, got: %q", v)
+		}
+		speculateAfter = d
+	}
+
+	advertiseAddr := os.Getenv("ADVERTISE_ADDR")
+	if advertiseAddr == "" {
+		// pod of the StatefulSet behind the headless service
+		advertiseAddr = fmt.Sprintf("%s.maroon:8080", podName)
+	}
+
 	return envVariables{
-		podName:       podName,
-		etcdEndpoints: endpoints,
+		podName:              podName,
+		advertiseAddr:        advertiseAddr,
+		discovery:            discovery,
+		dns:                  dns,
+		etcdEndpoints:        endpoints,
+		region:               region,
+		quorumNodesPerRegion: quorumNodesPerRegion,
+		protocol:             protocol,
+		clusterSize:          clusterSize,
+		walDir:               os.Getenv("WAL_DIR"),
+		walSync:              walSync,
+		snapshotEvery:        snapshotEvery,
+		sealPolicy:           sealPolicy,
+		dedupWindow:          dedupWindow,
+		peerQueueSize:        peerQueueSize,
+		peerOverflow:         peerOverflow,
+		peerTLS:              peerTLS,
+		replicaSelector:      replicaSelector,
+		speculateAfter:       speculateAfter,
 	}
 }
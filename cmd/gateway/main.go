@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/gateway"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	vars := envs()
+	logger.Infof(logger.Application, "Starting gateway: %s", vars.gatewayID)
+	logger.Infof(logger.Application, "Using maroon nodes: %v", vars.maroonNodes)
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   vars.etcdEndpoints,
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to create etcd client: %v", err)
+		os.Exit(1)
+	}
+	defer cli.Close()
+
+	nodes := make([]gatewayv1.GatewayServiceClient, 0, len(vars.maroonNodes))
+	for _, addr := range vars.maroonNodes {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			logger.Fatalf(logger.Network, "failed to connect to %v: %v", addr, err)
+		}
+		defer conn.Close()
+		nodes = append(nodes, gatewayv1.NewGatewayServiceClient(conn))
+	}
+
+	gw := gateway.New(vars.gatewayID, nodes, cli)
+	go func() {
+		if err := gw.Run(context.Background()); err != nil {
+			logger.Fatalf(logger.Application, "gateway stopped: %v", err)
+		}
+	}()
+
+	// TODO: add graceful shutdown
+	logger.Infof(logger.Network, "listening on :%s", vars.httpPort)
+	if err := http.ListenAndServe(":"+vars.httpPort, gateway.NewHandler(gw, vars.commitTimeout)); err != nil {
+		logger.Fatalf(logger.Network, "http server stopped: %v", err)
+	}
+}
+
+type envVariables struct {
+	gatewayID     string
+	etcdEndpoints []string
+	maroonNodes   []string
+
+	// optional
+	httpPort      string
+	commitTimeout time.Duration
+}
+
+func envs() envVariables {
+	gatewayID := os.Getenv("GATEWAY_ID")
+	if gatewayID == "" {
+		gatewayID = os.Getenv("POD_NAME")
+	}
+	if gatewayID == "" {
+		logger.Fatalf(logger.Application, "GATEWAY_ID or POD_NAME environment variable is required")
+	}
+
+	etcdEndpoints := os.Getenv("ETCD_ENDPOINTS")
+	if etcdEndpoints == "" {
+		logger.Fatalf(logger.Application, "ETCD_ENDPOINTS environment variable is required")
+	}
+
+	maroonNodes := os.Getenv("MAROON_NODES")
+	if maroonNodes == "" {
+		logger.Fatalf(logger.Application, "MAROON_NODES environment variable is required")
+	}
+
+	httpPort := os.Getenv("HTTP_PORT")
+	if httpPort == "" {
+		httpPort = "8000"
+	}
+
+	commitTimeout := 10 * time.Second
+	if v := os.Getenv("COMMIT_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			logger.Fatalf(logger.Application, "COMMIT_TIMEOUT should be a positive duration, got: %q", v)
+		}
+		commitTimeout = d
+	}
+
+	return envVariables{
+		gatewayID:     gatewayID,
+		etcdEndpoints: strings.Split(etcdEndpoints, ","),
+		maroonNodes:   strings.Split(maroonNodes, ","),
+		httpPort:      httpPort,
+		commitTimeout: commitTimeout,
+	}
+}
@@ -0,0 +1,132 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: proto/maroon/client/v1/client.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ClientService_SubmitOperation_FullMethodName = "/ClientService/SubmitOperation"
+)
+
+// ClientServiceClient is the client API for ClientService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// served by every maroon node for the services that write operations
+// followers forward the calls to the current leader
+type ClientServiceClient interface {
+	// streams status changes of the operation until it's committed
+	SubmitOperation(ctx context.Context, in *SubmitOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OperationStatus], error)
+}
+
+type clientServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClientServiceClient(cc grpc.ClientConnInterface) ClientServiceClient {
+	return &clientServiceClient{cc}
+}
+
+func (c *clientServiceClient) SubmitOperation(ctx context.Context, in *SubmitOperationRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[OperationStatus], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ClientService_ServiceDesc.Streams[0], ClientService_SubmitOperation_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubmitOperationRequest, OperationStatus]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClientService_SubmitOperationClient = grpc.ServerStreamingClient[OperationStatus]
+
+// ClientServiceServer is the server API for ClientService service.
+// All implementations must embed UnimplementedClientServiceServer
+// for forward compatibility.
+//
+// served by every maroon node for the services that write operations
+// followers forward the calls to the current leader
+type ClientServiceServer interface {
+	// streams status changes of the operation until it's committed
+	SubmitOperation(*SubmitOperationRequest, grpc.ServerStreamingServer[OperationStatus]) error
+	mustEmbedUnimplementedClientServiceServer()
+}
+
+// UnimplementedClientServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedClientServiceServer struct{}
+
+func (UnimplementedClientServiceServer) SubmitOperation(*SubmitOperationRequest, grpc.ServerStreamingServer[OperationStatus]) error {
+	return status.Errorf(codes.Unimplemented, "method SubmitOperation not implemented")
+}
+func (UnimplementedClientServiceServer) mustEmbedUnimplementedClientServiceServer() {}
+func (UnimplementedClientServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeClientServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClientServiceServer will
+// result in compilation errors.
+type UnsafeClientServiceServer interface {
+	mustEmbedUnimplementedClientServiceServer()
+}
+
+func RegisterClientServiceServer(s grpc.ServiceRegistrar, srv ClientServiceServer) {
+	// If the following call pancis, it indicates UnimplementedClientServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ClientService_ServiceDesc, srv)
+}
+
+func _ClientService_SubmitOperation_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubmitOperationRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClientServiceServer).SubmitOperation(m, &grpc.GenericServerStream[SubmitOperationRequest, OperationStatus]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ClientService_SubmitOperationServer = grpc.ServerStreamingServer[OperationStatus]
+
+// ClientService_ServiceDesc is the grpc.ServiceDesc for ClientService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ClientService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "ClientService",
+	HandlerType: (*ClientServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubmitOperation",
+			Handler:       _ClientService_SubmitOperation_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/maroon/client/v1/client.proto",
+}
@@ -0,0 +1,293 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: proto/maroon/gateway/v1/gateway.proto
+
+package v1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GatewayService_AcquireRange_FullMethodName   = "/GatewayService/AcquireRange"
+	GatewayService_NextRange_FullMethodName      = "/GatewayService/NextRange"
+	GatewayService_ReserveOffsets_FullMethodName = "/GatewayService/ReserveOffsets"
+	GatewayService_KeepAlive_FullMethodName      = "/GatewayService/KeepAlive"
+	GatewayService_SubmitTx_FullMethodName       = "/GatewayService/SubmitTx"
+)
+
+// GatewayServiceClient is the client API for GatewayService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// served by maroon nodes for communication gateways
+// see doc/key-range.md
+type GatewayServiceClient interface {
+	// grants a lease to the gateway and gives it a key range
+	AcquireRange(ctx context.Context, in *AcquireRangeRequest, opts ...grpc.CallOption) (*AcquireRangeResponse, error)
+	// one more range under the existing lease, when the current one is close to the end
+	NextRange(ctx context.Context, in *NextRangeRequest, opts ...grpc.CallOption) (*NextRangeResponse, error)
+	// gateway has to reserve offsets before using them
+	// so the range can be reclaimed safely if the gateway is gone
+	ReserveOffsets(ctx context.Context, in *ReserveOffsetsRequest, opts ...grpc.CallOption) (*ReserveOffsetsResponse, error)
+	KeepAlive(ctx context.Context, in *KeepAliveRequest, opts ...grpc.CallOption) (*KeepAliveResponse, error)
+	// client request with the id from the gateway's range
+	// node stores it and gossips to the other nodes
+	// the request is committed once the offset is in the committed vector
+	SubmitTx(ctx context.Context, in *SubmitTxRequest, opts ...grpc.CallOption) (*SubmitTxResponse, error)
+}
+
+type gatewayServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGatewayServiceClient(cc grpc.ClientConnInterface) GatewayServiceClient {
+	return &gatewayServiceClient{cc}
+}
+
+func (c *gatewayServiceClient) AcquireRange(ctx context.Context, in *AcquireRangeRequest, opts ...grpc.CallOption) (*AcquireRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AcquireRangeResponse)
+	err := c.cc.Invoke(ctx, GatewayService_AcquireRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayServiceClient) NextRange(ctx context.Context, in *NextRangeRequest, opts ...grpc.CallOption) (*NextRangeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NextRangeResponse)
+	err := c.cc.Invoke(ctx, GatewayService_NextRange_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayServiceClient) ReserveOffsets(ctx context.Context, in *ReserveOffsetsRequest, opts ...grpc.CallOption) (*ReserveOffsetsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReserveOffsetsResponse)
+	err := c.cc.Invoke(ctx, GatewayService_ReserveOffsets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayServiceClient) KeepAlive(ctx context.Context, in *KeepAliveRequest, opts ...grpc.CallOption) (*KeepAliveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(KeepAliveResponse)
+	err := c.cc.Invoke(ctx, GatewayService_KeepAlive_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gatewayServiceClient) SubmitTx(ctx context.Context, in *SubmitTxRequest, opts ...grpc.CallOption) (*SubmitTxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitTxResponse)
+	err := c.cc.Invoke(ctx, GatewayService_SubmitTx_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GatewayServiceServer is the server API for GatewayService service.
+// All implementations must embed UnimplementedGatewayServiceServer
+// for forward compatibility.
+//
+// served by maroon nodes for communication gateways
+// see doc/key-range.md
+type GatewayServiceServer interface {
+	// grants a lease to the gateway and gives it a key range
+	AcquireRange(context.Context, *AcquireRangeRequest) (*AcquireRangeResponse, error)
+	// one more range under the existing lease, when the current one is close to the end
+	NextRange(context.Context, *NextRangeRequest) (*NextRangeResponse, error)
+	// gateway has to reserve offsets before using them
+	// so the range can be reclaimed safely if the gateway is gone
+	ReserveOffsets(context.Context, *ReserveOffsetsRequest) (*ReserveOffsetsResponse, error)
+	KeepAlive(context.Context, *KeepAliveRequest) (*KeepAliveResponse, error)
+	// client request with the id from the gateway's range
+	// node stores it and gossips to the other nodes
+	// the request is committed once the offset is in the committed vector
+	SubmitTx(context.Context, *SubmitTxRequest) (*SubmitTxResponse, error)
+	mustEmbedUnimplementedGatewayServiceServer()
+}
+
+// UnimplementedGatewayServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGatewayServiceServer struct{}
+
+func (UnimplementedGatewayServiceServer) AcquireRange(context.Context, *AcquireRangeRequest) (*AcquireRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AcquireRange not implemented")
+}
+func (UnimplementedGatewayServiceServer) NextRange(context.Context, *NextRangeRequest) (*NextRangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextRange not implemented")
+}
+func (UnimplementedGatewayServiceServer) ReserveOffsets(context.Context, *ReserveOffsetsRequest) (*ReserveOffsetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReserveOffsets not implemented")
+}
+func (UnimplementedGatewayServiceServer) KeepAlive(context.Context, *KeepAliveRequest) (*KeepAliveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method KeepAlive not implemented")
+}
+func (UnimplementedGatewayServiceServer) SubmitTx(context.Context, *SubmitTxRequest) (*SubmitTxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitTx not implemented")
+}
+func (UnimplementedGatewayServiceServer) mustEmbedUnimplementedGatewayServiceServer() {}
+func (UnimplementedGatewayServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeGatewayServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GatewayServiceServer will
+// result in compilation errors.
+type UnsafeGatewayServiceServer interface {
+	mustEmbedUnimplementedGatewayServiceServer()
+}
+
+func RegisterGatewayServiceServer(s grpc.ServiceRegistrar, srv GatewayServiceServer) {
+	// If the following call pancis, it indicates UnimplementedGatewayServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GatewayService_ServiceDesc, srv)
+}
+
+func _GatewayService_AcquireRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AcquireRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServiceServer).AcquireRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayService_AcquireRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServiceServer).AcquireRange(ctx, req.(*AcquireRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayService_NextRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextRangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServiceServer).NextRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayService_NextRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServiceServer).NextRange(ctx, req.(*NextRangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayService_ReserveOffsets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveOffsetsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServiceServer).ReserveOffsets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayService_ReserveOffsets_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServiceServer).ReserveOffsets(ctx, req.(*ReserveOffsetsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayService_KeepAlive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KeepAliveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServiceServer).KeepAlive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayService_KeepAlive_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServiceServer).KeepAlive(ctx, req.(*KeepAliveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GatewayService_SubmitTx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitTxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GatewayServiceServer).SubmitTx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GatewayService_SubmitTx_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GatewayServiceServer).SubmitTx(ctx, req.(*SubmitTxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GatewayService_ServiceDesc is the grpc.ServiceDesc for GatewayService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GatewayService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "GatewayService",
+	HandlerType: (*GatewayServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AcquireRange",
+			Handler:    _GatewayService_AcquireRange_Handler,
+		},
+		{
+			MethodName: "NextRange",
+			Handler:    _GatewayService_NextRange_Handler,
+		},
+		{
+			MethodName: "ReserveOffsets",
+			Handler:    _GatewayService_ReserveOffsets_Handler,
+		},
+		{
+			MethodName: "KeepAlive",
+			Handler:    _GatewayService_KeepAlive_Handler,
+		},
+		{
+			MethodName: "SubmitTx",
+			Handler:    _GatewayService_SubmitTx_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/maroon/gateway/v1/gateway.proto",
+}
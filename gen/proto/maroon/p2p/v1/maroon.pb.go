@@ -117,6 +117,703 @@ func (x *AddTxResponse) GetAcced() bool {
 	return false
 }
 
+type Tx struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Tx) Reset() {
+	*x = Tx{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Tx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tx) ProtoMessage() {}
+
+func (x *Tx) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tx.ProtoReflect.Descriptor instead.
+func (*Tx) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Tx) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Tx) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type ReplicateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// position of the first tx in the leader's queue for the follower
+	// txs follow it one by one, after a reconnect the unacked ones are sent again
+	FirstSeq      uint64 `protobuf:"varint,1,opt,name=first_seq,json=firstSeq,proto3" json:"first_seq,omitempty"`
+	Txs           []*Tx  `protobuf:"bytes,2,rep,name=txs,proto3" json:"txs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateRequest) Reset() {
+	*x = ReplicateRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateRequest) ProtoMessage() {}
+
+func (x *ReplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReplicateRequest) GetFirstSeq() uint64 {
+	if x != nil {
+		return x.FirstSeq
+	}
+	return 0
+}
+
+func (x *ReplicateRequest) GetTxs() []*Tx {
+	if x != nil {
+		return x.Txs
+	}
+	return nil
+}
+
+type ReplicateAck struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// every tx up to this position is stored
+	AckedSeq      uint64 `protobuf:"varint,1,opt,name=acked_seq,json=ackedSeq,proto3" json:"acked_seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateAck) Reset() {
+	*x = ReplicateAck{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateAck) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateAck) ProtoMessage() {}
+
+func (x *ReplicateAck) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateAck.ProtoReflect.Descriptor instead.
+func (*ReplicateAck) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReplicateAck) GetAckedSeq() uint64 {
+	if x != nil {
+		return x.AckedSeq
+	}
+	return 0
+}
+
+type GetTxsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTxsRequest) Reset() {
+	*x = GetTxsRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTxsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTxsRequest) ProtoMessage() {}
+
+func (x *GetTxsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTxsRequest.ProtoReflect.Descriptor instead.
+func (*GetTxsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetTxsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type GetTxsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Txs           []*Tx                  `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTxsResponse) Reset() {
+	*x = GetTxsResponse{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTxsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTxsResponse) ProtoMessage() {}
+
+func (x *GetTxsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTxsResponse.ProtoReflect.Descriptor instead.
+func (*GetTxsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetTxsResponse) GetTxs() []*Tx {
+	if x != nil {
+		return x.Txs
+	}
+	return nil
+}
+
+type GetPendingTxsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPendingTxsRequest) Reset() {
+	*x = GetPendingTxsRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPendingTxsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPendingTxsRequest) ProtoMessage() {}
+
+func (x *GetPendingTxsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPendingTxsRequest.ProtoReflect.Descriptor instead.
+func (*GetPendingTxsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{7}
+}
+
+type GetPendingTxsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Txs           []*Tx                  `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPendingTxsResponse) Reset() {
+	*x = GetPendingTxsResponse{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPendingTxsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPendingTxsResponse) ProtoMessage() {}
+
+func (x *GetPendingTxsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPendingTxsResponse.ProtoReflect.Descriptor instead.
+func (*GetPendingTxsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetPendingTxsResponse) GetTxs() []*Tx {
+	if x != nil {
+		return x.Txs
+	}
+	return nil
+}
+
+type OffsetTx struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	RangeIndex uint64                 `protobuf:"varint,1,opt,name=range_index,json=rangeIndex,proto3" json:"range_index,omitempty"`
+	Offset     uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Payload    []byte                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	// see gateway.v1.SubmitTxRequest
+	Noop          bool `protobuf:"varint,4,opt,name=noop,proto3" json:"noop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OffsetTx) Reset() {
+	*x = OffsetTx{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OffsetTx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OffsetTx) ProtoMessage() {}
+
+func (x *OffsetTx) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OffsetTx.ProtoReflect.Descriptor instead.
+func (*OffsetTx) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *OffsetTx) GetRangeIndex() uint64 {
+	if x != nil {
+		return x.RangeIndex
+	}
+	return 0
+}
+
+func (x *OffsetTx) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *OffsetTx) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *OffsetTx) GetNoop() bool {
+	if x != nil {
+		return x.Noop
+	}
+	return false
+}
+
+type GossipTxsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Txs           []*OffsetTx            `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipTxsRequest) Reset() {
+	*x = GossipTxsRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipTxsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipTxsRequest) ProtoMessage() {}
+
+func (x *GossipTxsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipTxsRequest.ProtoReflect.Descriptor instead.
+func (*GossipTxsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GossipTxsRequest) GetTxs() []*OffsetTx {
+	if x != nil {
+		return x.Txs
+	}
+	return nil
+}
+
+type GossipTxsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipTxsResponse) Reset() {
+	*x = GossipTxsResponse{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipTxsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipTxsResponse) ProtoMessage() {}
+
+func (x *GossipTxsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipTxsResponse.ProtoReflect.Descriptor instead.
+func (*GossipTxsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{11}
+}
+
+type RangeOffset struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RangeIndex    uint64                 `protobuf:"varint,1,opt,name=range_index,json=rangeIndex,proto3" json:"range_index,omitempty"`
+	Offset        uint64                 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RangeOffset) Reset() {
+	*x = RangeOffset{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RangeOffset) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RangeOffset) ProtoMessage() {}
+
+func (x *RangeOffset) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RangeOffset.ProtoReflect.Descriptor instead.
+func (*RangeOffset) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RangeOffset) GetRangeIndex() uint64 {
+	if x != nil {
+		return x.RangeIndex
+	}
+	return 0
+}
+
+func (x *RangeOffset) GetOffset() uint64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type PublishVectorRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Offsets       []*RangeOffset         `protobuf:"bytes,2,rep,name=offsets,proto3" json:"offsets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishVectorRequest) Reset() {
+	*x = PublishVectorRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishVectorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishVectorRequest) ProtoMessage() {}
+
+func (x *PublishVectorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishVectorRequest.ProtoReflect.Descriptor instead.
+func (*PublishVectorRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PublishVectorRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *PublishVectorRequest) GetOffsets() []*RangeOffset {
+	if x != nil {
+		return x.Offsets
+	}
+	return nil
+}
+
+type PublishVectorResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PublishVectorResponse) Reset() {
+	*x = PublishVectorResponse{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PublishVectorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PublishVectorResponse) ProtoMessage() {}
+
+func (x *PublishVectorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PublishVectorResponse.ProtoReflect.Descriptor instead.
+func (*PublishVectorResponse) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{14}
+}
+
+type GetSnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSnapshotRequest) Reset() {
+	*x = GetSnapshotRequest{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSnapshotRequest) ProtoMessage() {}
+
+func (x *GetSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*GetSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{15}
+}
+
+type SnapshotChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// last block in the snapshot, the same in every chunk
+	BlockNumber   int64  `protobuf:"varint,1,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	Data          []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotChunk) Reset() {
+	*x = SnapshotChunk{}
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotChunk) ProtoMessage() {}
+
+func (x *SnapshotChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_maroon_p2p_v1_maroon_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotChunk.ProtoReflect.Descriptor instead.
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *SnapshotChunk) GetBlockNumber() int64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *SnapshotChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
 var File_proto_maroon_p2p_v1_maroon_proto protoreflect.FileDescriptor
 
 var file_proto_maroon_p2p_v1_maroon_proto_rawDesc = string([]byte{
@@ -128,14 +825,83 @@ var file_proto_maroon_p2p_v1_maroon_proto_rawDesc = string([]byte{
 	0x01, 0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x22, 0x25, 0x0a, 0x0d,
 	0x41, 0x64, 0x64, 0x54, 0x78, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x14, 0x0a,
 	0x05, 0x61, 0x63, 0x63, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x61, 0x63,
-	0x63, 0x65, 0x64, 0x32, 0x34, 0x0a, 0x0a, 0x50, 0x32, 0x50, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0x12, 0x26, 0x0a, 0x05, 0x41, 0x64, 0x64, 0x54, 0x78, 0x12, 0x0d, 0x2e, 0x41, 0x64, 0x64,
-	0x54, 0x78, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x41, 0x64, 0x64, 0x54,
-	0x78, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74,
-	0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6b, 0x61, 0x6e, 0x74, 0x73, 0x65, 0x76,
-	0x6f, 0x69, 0x2f, 0x74, 0x65, 0x73, 0x74, 0x2d, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d,
-	0x65, 0x6e, 0x74, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x6d, 0x61, 0x72, 0x6f, 0x6f, 0x6e, 0x2f, 0x70,
-	0x32, 0x70, 0x2f, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x63, 0x65, 0x64, 0x22, 0x2e, 0x0a, 0x02, 0x54, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79,
+	0x6c, 0x6f, 0x61, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c,
+	0x6f, 0x61, 0x64, 0x22, 0x46, 0x0a, 0x10, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x66, 0x69, 0x72, 0x73, 0x74,
+	0x5f, 0x73, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x66, 0x69, 0x72, 0x73,
+	0x74, 0x53, 0x65, 0x71, 0x12, 0x15, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x03, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x22, 0x2b, 0x0a, 0x0c, 0x52,
+	0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x41, 0x63, 0x6b, 0x12, 0x1b, 0x0a, 0x09, 0x61,
+	0x63, 0x6b, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08,
+	0x61, 0x63, 0x6b, 0x65, 0x64, 0x53, 0x65, 0x71, 0x22, 0x21, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x54,
+	0x78, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x27, 0x0a, 0x0e, 0x47,
+	0x65, 0x74, 0x54, 0x78, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x15, 0x0a,
+	0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x03, 0x2e, 0x54, 0x78, 0x52,
+	0x03, 0x74, 0x78, 0x73, 0x22, 0x16, 0x0a, 0x14, 0x47, 0x65, 0x74, 0x50, 0x65, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x2e, 0x0a, 0x15,
+	0x47, 0x65, 0x74, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x54, 0x78, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x15, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x03, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x22, 0x71, 0x0a, 0x08,
+	0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x54, 0x78, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x61, 0x6e, 0x67,
+	0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x72,
+	0x61, 0x6e, 0x67, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66,
+	0x73, 0x65, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x07, 0x70, 0x61, 0x79, 0x6c, 0x6f, 0x61, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x6f, 0x6f, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x6e, 0x6f, 0x6f, 0x70, 0x22,
+	0x2f, 0x0a, 0x10, 0x47, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x09, 0x2e, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73,
+	0x22, 0x13, 0x0a, 0x11, 0x47, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x54, 0x78, 0x73, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x46, 0x0a, 0x0b, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x4f, 0x66,
+	0x66, 0x73, 0x65, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x72, 0x61, 0x6e, 0x67, 0x65, 0x5f, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x72, 0x61, 0x6e, 0x67, 0x65,
+	0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x22, 0x57, 0x0a,
+	0x14, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x26,
+	0x0a, 0x07, 0x6f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x0c, 0x2e, 0x52, 0x61, 0x6e, 0x67, 0x65, 0x4f, 0x66, 0x66, 0x73, 0x65, 0x74, 0x52, 0x07, 0x6f,
+	0x66, 0x66, 0x73, 0x65, 0x74, 0x73, 0x22, 0x17, 0x0a, 0x15, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73,
+	0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
+	0x14, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x46, 0x0a, 0x0d, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f,
+	0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x12, 0x21, 0x0a, 0x0c, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f,
+	0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x62, 0x6c,
+	0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x32, 0xfc, 0x02,
+	0x0a, 0x0a, 0x50, 0x32, 0x50, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x26, 0x0a, 0x05,
+	0x41, 0x64, 0x64, 0x54, 0x78, 0x12, 0x0d, 0x2e, 0x41, 0x64, 0x64, 0x54, 0x78, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x41, 0x64, 0x64, 0x54, 0x78, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x31, 0x0a, 0x09, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74,
+	0x65, 0x12, 0x11, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65,
+	0x41, 0x63, 0x6b, 0x28, 0x01, 0x30, 0x01, 0x12, 0x29, 0x0a, 0x06, 0x47, 0x65, 0x74, 0x54, 0x78,
+	0x73, 0x12, 0x0e, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x0f, 0x2e, 0x47, 0x65, 0x74, 0x54, 0x78, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3e, 0x0a, 0x0d, 0x47, 0x65, 0x74, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67,
+	0x54, 0x78, 0x73, 0x12, 0x15, 0x2e, 0x47, 0x65, 0x74, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67,
+	0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x47, 0x65, 0x74,
+	0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x54, 0x78, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x32, 0x0a, 0x09, 0x47, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x54, 0x78, 0x73, 0x12,
+	0x11, 0x2e, 0x47, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x54, 0x78, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x12, 0x2e, 0x47, 0x6f, 0x73, 0x73, 0x69, 0x70, 0x54, 0x78, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3e, 0x0a, 0x0d, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73,
+	0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x15, 0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73,
+	0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16,
+	0x2e, 0x50, 0x75, 0x62, 0x6c, 0x69, 0x73, 0x68, 0x56, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x34, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x53, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x13, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0e, 0x2e, 0x53, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x43, 0x68, 0x75, 0x6e, 0x6b, 0x30, 0x01, 0x42, 0x3a, 0x5a, 0x38,
+	0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x6b, 0x61, 0x6e, 0x74,
+	0x73, 0x65, 0x76, 0x6f, 0x69, 0x2f, 0x74, 0x65, 0x73, 0x74, 0x2d, 0x65, 0x6e, 0x76, 0x69, 0x72,
+	0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x2f, 0x67, 0x65, 0x6e, 0x2f, 0x6d, 0x61, 0x72, 0x6f, 0x6f,
+	0x6e, 0x2f, 0x70, 0x32, 0x70, 0x2f, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 })
 
 var (
@@ -150,19 +916,51 @@ func file_proto_maroon_p2p_v1_maroon_proto_rawDescGZIP() []byte {
 	return file_proto_maroon_p2p_v1_maroon_proto_rawDescData
 }
 
-var file_proto_maroon_p2p_v1_maroon_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_proto_maroon_p2p_v1_maroon_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_proto_maroon_p2p_v1_maroon_proto_goTypes = []any{
-	(*AddTxRequest)(nil),  // 0: AddTxRequest
-	(*AddTxResponse)(nil), // 1: AddTxResponse
+	(*AddTxRequest)(nil),          // 0: AddTxRequest
+	(*AddTxResponse)(nil),         // 1: AddTxResponse
+	(*Tx)(nil),                    // 2: Tx
+	(*ReplicateRequest)(nil),      // 3: ReplicateRequest
+	(*ReplicateAck)(nil),          // 4: ReplicateAck
+	(*GetTxsRequest)(nil),         // 5: GetTxsRequest
+	(*GetTxsResponse)(nil),        // 6: GetTxsResponse
+	(*GetPendingTxsRequest)(nil),  // 7: GetPendingTxsRequest
+	(*GetPendingTxsResponse)(nil), // 8: GetPendingTxsResponse
+	(*OffsetTx)(nil),              // 9: OffsetTx
+	(*GossipTxsRequest)(nil),      // 10: GossipTxsRequest
+	(*GossipTxsResponse)(nil),     // 11: GossipTxsResponse
+	(*RangeOffset)(nil),           // 12: RangeOffset
+	(*PublishVectorRequest)(nil),  // 13: PublishVectorRequest
+	(*PublishVectorResponse)(nil), // 14: PublishVectorResponse
+	(*GetSnapshotRequest)(nil),    // 15: GetSnapshotRequest
+	(*SnapshotChunk)(nil),         // 16: SnapshotChunk
 }
 var file_proto_maroon_p2p_v1_maroon_proto_depIdxs = []int32{
-	0, // 0: P2PService.AddTx:input_type -> AddTxRequest
-	1, // 1: P2PService.AddTx:output_type -> AddTxResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: ReplicateRequest.txs:type_name -> Tx
+	2,  // 1: GetTxsResponse.txs:type_name -> Tx
+	2,  // 2: GetPendingTxsResponse.txs:type_name -> Tx
+	9,  // 3: GossipTxsRequest.txs:type_name -> OffsetTx
+	12, // 4: PublishVectorRequest.offsets:type_name -> RangeOffset
+	0,  // 5: P2PService.AddTx:input_type -> AddTxRequest
+	3,  // 6: P2PService.Replicate:input_type -> ReplicateRequest
+	5,  // 7: P2PService.GetTxs:input_type -> GetTxsRequest
+	7,  // 8: P2PService.GetPendingTxs:input_type -> GetPendingTxsRequest
+	10, // 9: P2PService.GossipTxs:input_type -> GossipTxsRequest
+	13, // 10: P2PService.PublishVector:input_type -> PublishVectorRequest
+	15, // 11: P2PService.GetSnapshot:input_type -> GetSnapshotRequest
+	1,  // 12: P2PService.AddTx:output_type -> AddTxResponse
+	4,  // 13: P2PService.Replicate:output_type -> ReplicateAck
+	6,  // 14: P2PService.GetTxs:output_type -> GetTxsResponse
+	8,  // 15: P2PService.GetPendingTxs:output_type -> GetPendingTxsResponse
+	11, // 16: P2PService.GossipTxs:output_type -> GossipTxsResponse
+	14, // 17: P2PService.PublishVector:output_type -> PublishVectorResponse
+	16, // 18: P2PService.GetSnapshot:output_type -> SnapshotChunk
+	12, // [12:19] is the sub-list for method output_type
+	5,  // [5:12] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_maroon_p2p_v1_maroon_proto_init() }
@@ -176,7 +974,7 @@ func file_proto_maroon_p2p_v1_maroon_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_maroon_p2p_v1_maroon_proto_rawDesc), len(file_proto_maroon_p2p_v1_maroon_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
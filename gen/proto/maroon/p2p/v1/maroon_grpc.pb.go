@@ -19,14 +19,36 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	P2PService_AddTx_FullMethodName = "/P2PService/AddTx"
+	P2PService_AddTx_FullMethodName         = "/P2PService/AddTx"
+	P2PService_Replicate_FullMethodName     = "/P2PService/Replicate"
+	P2PService_GetTxs_FullMethodName        = "/P2PService/GetTxs"
+	P2PService_GetPendingTxs_FullMethodName = "/P2PService/GetPendingTxs"
+	P2PService_GossipTxs_FullMethodName     = "/P2PService/GossipTxs"
+	P2PService_PublishVector_FullMethodName = "/P2PService/PublishVector"
+	P2PService_GetSnapshot_FullMethodName   = "/P2PService/GetSnapshot"
 )
 
 // P2PServiceClient is the client API for P2PService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type P2PServiceClient interface {
+	// single transaction, leaders use Replicate instead
 	AddTx(ctx context.Context, in *AddTxRequest, opts ...grpc.CallOption) (*AddTxResponse, error)
+	// leader pushes transactions to the follower in order
+	// follower acks them cumulatively once they're stored
+	Replicate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ReplicateRequest, ReplicateAck], error)
+	// returns transactions known by the node, unknown ids are skipped
+	GetTxs(ctx context.Context, in *GetTxsRequest, opts ...grpc.CallOption) (*GetTxsResponse, error)
+	// transactions the node acked but that are not in any block yet
+	// new leader collects them so acked writes of the previous leader are not lost
+	GetPendingTxs(ctx context.Context, in *GetPendingTxsRequest, opts ...grpc.CallOption) (*GetPendingTxsResponse, error)
+	// offset vector protocol, see doc/communication-gateway-maroon.md
+	// transactions received by a node from the gateway
+	GossipTxs(ctx context.Context, in *GossipTxsRequest, opts ...grpc.CallOption) (*GossipTxsResponse, error)
+	// uncommitted local vector of the node
+	PublishVector(ctx context.Context, in *PublishVectorRequest, opts ...grpc.CallOption) (*PublishVectorResponse, error)
+	// latest snapshot of the node for followers that are far behind
+	GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SnapshotChunk], error)
 }
 
 type p2PServiceClient struct {
@@ -47,11 +69,99 @@ func (c *p2PServiceClient) AddTx(ctx context.Context, in *AddTxRequest, opts ...
 	return out, nil
 }
 
+func (c *p2PServiceClient) Replicate(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ReplicateRequest, ReplicateAck], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &P2PService_ServiceDesc.Streams[0], P2PService_Replicate_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReplicateRequest, ReplicateAck]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2PService_ReplicateClient = grpc.BidiStreamingClient[ReplicateRequest, ReplicateAck]
+
+func (c *p2PServiceClient) GetTxs(ctx context.Context, in *GetTxsRequest, opts ...grpc.CallOption) (*GetTxsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTxsResponse)
+	err := c.cc.Invoke(ctx, P2PService_GetTxs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PServiceClient) GetPendingTxs(ctx context.Context, in *GetPendingTxsRequest, opts ...grpc.CallOption) (*GetPendingTxsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPendingTxsResponse)
+	err := c.cc.Invoke(ctx, P2PService_GetPendingTxs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PServiceClient) GossipTxs(ctx context.Context, in *GossipTxsRequest, opts ...grpc.CallOption) (*GossipTxsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GossipTxsResponse)
+	err := c.cc.Invoke(ctx, P2PService_GossipTxs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PServiceClient) PublishVector(ctx context.Context, in *PublishVectorRequest, opts ...grpc.CallOption) (*PublishVectorResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PublishVectorResponse)
+	err := c.cc.Invoke(ctx, P2PService_PublishVector_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *p2PServiceClient) GetSnapshot(ctx context.Context, in *GetSnapshotRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SnapshotChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &P2PService_ServiceDesc.Streams[1], P2PService_GetSnapshot_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetSnapshotRequest, SnapshotChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2PService_GetSnapshotClient = grpc.ServerStreamingClient[SnapshotChunk]
+
 // P2PServiceServer is the server API for P2PService service.
 // All implementations must embed UnimplementedP2PServiceServer
 // for forward compatibility.
 type P2PServiceServer interface {
+	// single transaction, leaders use Replicate instead
 	AddTx(context.Context, *AddTxRequest) (*AddTxResponse, error)
+	// leader pushes transactions to the follower in order
+	// follower acks them cumulatively once they're stored
+	Replicate(grpc.BidiStreamingServer[ReplicateRequest, ReplicateAck]) error
+	// returns transactions known by the node, unknown ids are skipped
+	GetTxs(context.Context, *GetTxsRequest) (*GetTxsResponse, error)
+	// transactions the node acked but that are not in any block yet
+	// new leader collects them so acked writes of the previous leader are not lost
+	GetPendingTxs(context.Context, *GetPendingTxsRequest) (*GetPendingTxsResponse, error)
+	// offset vector protocol, see doc/communication-gateway-maroon.md
+	// transactions received by a node from the gateway
+	GossipTxs(context.Context, *GossipTxsRequest) (*GossipTxsResponse, error)
+	// uncommitted local vector of the node
+	PublishVector(context.Context, *PublishVectorRequest) (*PublishVectorResponse, error)
+	// latest snapshot of the node for followers that are far behind
+	GetSnapshot(*GetSnapshotRequest, grpc.ServerStreamingServer[SnapshotChunk]) error
 	mustEmbedUnimplementedP2PServiceServer()
 }
 
@@ -65,6 +175,24 @@ type UnimplementedP2PServiceServer struct{}
 func (UnimplementedP2PServiceServer) AddTx(context.Context, *AddTxRequest) (*AddTxResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddTx not implemented")
 }
+func (UnimplementedP2PServiceServer) Replicate(grpc.BidiStreamingServer[ReplicateRequest, ReplicateAck]) error {
+	return status.Errorf(codes.Unimplemented, "method Replicate not implemented")
+}
+func (UnimplementedP2PServiceServer) GetTxs(context.Context, *GetTxsRequest) (*GetTxsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTxs not implemented")
+}
+func (UnimplementedP2PServiceServer) GetPendingTxs(context.Context, *GetPendingTxsRequest) (*GetPendingTxsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPendingTxs not implemented")
+}
+func (UnimplementedP2PServiceServer) GossipTxs(context.Context, *GossipTxsRequest) (*GossipTxsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GossipTxs not implemented")
+}
+func (UnimplementedP2PServiceServer) PublishVector(context.Context, *PublishVectorRequest) (*PublishVectorResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublishVector not implemented")
+}
+func (UnimplementedP2PServiceServer) GetSnapshot(*GetSnapshotRequest, grpc.ServerStreamingServer[SnapshotChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method GetSnapshot not implemented")
+}
 func (UnimplementedP2PServiceServer) mustEmbedUnimplementedP2PServiceServer() {}
 func (UnimplementedP2PServiceServer) testEmbeddedByValue()                    {}
 
@@ -104,6 +232,96 @@ func _P2PService_AddTx_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _P2PService_Replicate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(P2PServiceServer).Replicate(&grpc.GenericServerStream[ReplicateRequest, ReplicateAck]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2PService_ReplicateServer = grpc.BidiStreamingServer[ReplicateRequest, ReplicateAck]
+
+func _P2PService_GetTxs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTxsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServiceServer).GetTxs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2PService_GetTxs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServiceServer).GetTxs(ctx, req.(*GetTxsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2PService_GetPendingTxs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPendingTxsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServiceServer).GetPendingTxs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2PService_GetPendingTxs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServiceServer).GetPendingTxs(ctx, req.(*GetPendingTxsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2PService_GossipTxs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipTxsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServiceServer).GossipTxs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2PService_GossipTxs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServiceServer).GossipTxs(ctx, req.(*GossipTxsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2PService_PublishVector_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublishVectorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(P2PServiceServer).PublishVector(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: P2PService_PublishVector_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(P2PServiceServer).PublishVector(ctx, req.(*PublishVectorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _P2PService_GetSnapshot_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetSnapshotRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(P2PServiceServer).GetSnapshot(m, &grpc.GenericServerStream[GetSnapshotRequest, SnapshotChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type P2PService_GetSnapshotServer = grpc.ServerStreamingServer[SnapshotChunk]
+
 // P2PService_ServiceDesc is the grpc.ServiceDesc for P2PService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,7 +333,35 @@ var P2PService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AddTx",
 			Handler:    _P2PService_AddTx_Handler,
 		},
+		{
+			MethodName: "GetTxs",
+			Handler:    _P2PService_GetTxs_Handler,
+		},
+		{
+			MethodName: "GetPendingTxs",
+			Handler:    _P2PService_GetPendingTxs_Handler,
+		},
+		{
+			MethodName: "GossipTxs",
+			Handler:    _P2PService_GossipTxs_Handler,
+		},
+		{
+			MethodName: "PublishVector",
+			Handler:    _P2PService_PublishVector_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Replicate",
+			Handler:       _P2PService_Replicate_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GetSnapshot",
+			Handler:       _P2PService_GetSnapshot_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/maroon/p2p/v1/maroon.proto",
 }
@@ -0,0 +1,112 @@
+package clientapi
+
+import (
+	"errors"
+	"io"
+
+	clientv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/client/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *serv) SubmitOperation(req *clientv1.SubmitOperationRequest, stream grpc.ServerStreamingServer[clientv1.OperationStatus]) error {
+	if req.Operation == nil {
+		return status.Error(codes.InvalidArgument, "operation is required")
+	}
+	op := maroon.Operation{
+		OpType:   maroon.OperationType(req.Operation.Type),
+		Value:    req.Operation.Value,
+		ClientID: req.Operation.ClientId,
+		Seq:      req.Operation.Seq,
+	}
+
+	statusCh, err := s.ops.AddOp(op)
+	if errors.Is(err, maroon.ErrNotLeader) {
+		if req.Forwarded {
+			// leader has changed in between, client has to retry
+			return status.Error(codes.Unavailable, err.Error())
+		}
+		return s.forward(req, stream)
+	}
+	if errors.Is(err, maroon.ErrOverloaded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case st, ok := <-statusCh:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(statusToProto(st)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sends the operation to the leader and streams its statuses back
+func (s *serv) forward(req *clientv1.SubmitOperationRequest, stream grpc.ServerStreamingServer[clientv1.OperationStatus]) error {
+	ctx := stream.Context()
+	addr, err := s.leader.LeaderAddr(ctx)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "no leader: %v", err)
+	}
+	client, err := s.leaderClient(addr)
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to connect to leader %v: %v", addr, err)
+	}
+
+	logger.Debugf(logger.Network, "forward operation to the leader %v", addr)
+	leaderStream, err := client.SubmitOperation(ctx, &clientv1.SubmitOperationRequest{
+		Operation: req.Operation,
+		Forwarded: true,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		st, err := leaderStream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(st); err != nil {
+			return err
+		}
+	}
+}
+
+func statusToProto(st maroon.OpStatus) *clientv1.OperationStatus {
+	var state clientv1.OperationState
+	switch st.State {
+	case maroon.OpAccepted:
+		state = clientv1.OperationState_OPERATION_STATE_ACCEPTED
+	case maroon.OpDistributed:
+		state = clientv1.OperationState_OPERATION_STATE_DISTRIBUTED
+	case maroon.OpInBlock:
+		state = clientv1.OperationState_OPERATION_STATE_IN_BLOCK
+	case maroon.OpCommitted:
+		state = clientv1.OperationState_OPERATION_STATE_COMMITTED
+	}
+	res := &clientv1.OperationStatus{
+		Id:          st.ID,
+		State:       state,
+		BlockNumber: st.Block,
+		Result:      st.Result,
+	}
+	if st.Err != nil {
+		res.Error = st.Err.Error()
+	}
+	return res
+}
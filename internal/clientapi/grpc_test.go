@@ -0,0 +1,76 @@
+package clientapi
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	clientv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/client/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type submitter struct {
+	leader bool
+}
+
+func (s submitter) AddOp(op maroon.Operation) (<-chan maroon.OpStatus, error) {
+	if !s.leader {
+		return nil, maroon.ErrNotLeader
+	}
+	id := op.Hash()
+	ch := make(chan maroon.OpStatus, 4)
+	ch <- maroon.OpStatus{ID: id, State: maroon.OpAccepted}
+	ch <- maroon.OpStatus{ID: id, State: maroon.OpDistributed}
+	ch <- maroon.OpStatus{ID: id, State: maroon.OpInBlock, Block: 7}
+	ch <- maroon.OpStatus{ID: id, State: maroon.OpCommitted, Block: 7}
+	close(ch)
+	return ch, nil
+}
+
+type staticLeader string
+
+func (l staticLeader) LeaderAddr(context.Context) (string, error) {
+	return string(l), nil
+}
+
+func TestFollowerForwardsToLeader(t *testing.T) {
+	leader := New("8098", submitter{leader: true}, staticLeader("localhost:8098"))
+	follower := New("8099", submitter{}, staticLeader("localhost:8098"))
+	go leader.Start()
+	go follower.Start()
+	defer leader.Stop()
+	defer follower.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := grpc.NewClient("localhost:8099", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream, err := clientv1.NewClientServiceClient(conn).SubmitOperation(context.Background(), &clientv1.SubmitOperationRequest{
+		Operation: &clientv1.Operation{Type: int64(maroon.PrintTimestamp), Value: "1"},
+	})
+	require.NoError(t, err)
+
+	var states []clientv1.OperationState
+	var last *clientv1.OperationStatus
+	for {
+		st, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		states = append(states, st.State)
+		last = st
+	}
+	require.Equal(t, []clientv1.OperationState{
+		clientv1.OperationState_OPERATION_STATE_ACCEPTED,
+		clientv1.OperationState_OPERATION_STATE_DISTRIBUTED,
+		clientv1.OperationState_OPERATION_STATE_IN_BLOCK,
+		clientv1.OperationState_OPERATION_STATE_COMMITTED,
+	}, states)
+	require.Equal(t, int64(7), last.BlockNumber)
+}
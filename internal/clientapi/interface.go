@@ -0,0 +1,22 @@
+package clientapi
+
+import (
+	"context"
+
+	"github.com/akantsevoi/test-environment/internal/maroon"
+)
+
+type Server interface {
+	// Blocking function
+	Start()
+	Stop()
+}
+
+type OpSubmitter interface {
+	AddOp(op maroon.Operation) (<-chan maroon.OpStatus, error)
+}
+
+// address of the leader's client api
+type LeaderResolver interface {
+	LeaderAddr(ctx context.Context) (string, error)
+}
@@ -0,0 +1,40 @@
+package clientapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type ETCD interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+}
+
+type etcdLeader struct {
+	cli       ETCD
+	leaderKey string
+	// client api address of the node
+	addr func(nodeID string) string
+}
+
+// reads the leader's node id from the election key
+func NewETCDLeaderResolver(cli ETCD, leaderKey string, addr func(nodeID string) string) LeaderResolver {
+	return &etcdLeader{
+		cli:       cli,
+		leaderKey: leaderKey,
+		addr:      addr,
+	}
+}
+
+func (l *etcdLeader) LeaderAddr(ctx context.Context) (string, error) {
+	resp, err := l.cli.Get(ctx, l.leaderKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get leader: %w", err)
+	}
+	if len(resp.Kvs) == 0 {
+		return "", errors.New("leader is not elected")
+	}
+	return l.addr(string(resp.Kvs[0].Value)), nil
+}
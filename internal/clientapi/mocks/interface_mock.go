@@ -0,0 +1,144 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/clientapi/interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/clientapi/interface.go -destination=internal/clientapi/mocks/interface_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	maroon "github.com/akantsevoi/test-environment/internal/maroon"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockServer is a mock of Server interface.
+type MockServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockServerMockRecorder
+	isgomock struct{}
+}
+
+// MockServerMockRecorder is the mock recorder for MockServer.
+type MockServerMockRecorder struct {
+	mock *MockServer
+}
+
+// NewMockServer creates a new mock instance.
+func NewMockServer(ctrl *gomock.Controller) *MockServer {
+	mock := &MockServer{ctrl: ctrl}
+	mock.recorder = &MockServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServer) EXPECT() *MockServerMockRecorder {
+	return m.recorder
+}
+
+// Start mocks base method.
+func (m *MockServer) Start() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Start")
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockServerMockRecorder) Start() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockServer)(nil).Start))
+}
+
+// Stop mocks base method.
+func (m *MockServer) Stop() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Stop")
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *MockServerMockRecorder) Stop() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockServer)(nil).Stop))
+}
+
+// MockOpSubmitter is a mock of OpSubmitter interface.
+type MockOpSubmitter struct {
+	ctrl     *gomock.Controller
+	recorder *MockOpSubmitterMockRecorder
+	isgomock struct{}
+}
+
+// MockOpSubmitterMockRecorder is the mock recorder for MockOpSubmitter.
+type MockOpSubmitterMockRecorder struct {
+	mock *MockOpSubmitter
+}
+
+// NewMockOpSubmitter creates a new mock instance.
+func NewMockOpSubmitter(ctrl *gomock.Controller) *MockOpSubmitter {
+	mock := &MockOpSubmitter{ctrl: ctrl}
+	mock.recorder = &MockOpSubmitterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOpSubmitter) EXPECT() *MockOpSubmitterMockRecorder {
+	return m.recorder
+}
+
+// AddOp mocks base method.
+func (m *MockOpSubmitter) AddOp(op maroon.Operation) (<-chan maroon.OpStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddOp", op)
+	ret0, _ := ret[0].(<-chan maroon.OpStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddOp indicates an expected call of AddOp.
+func (mr *MockOpSubmitterMockRecorder) AddOp(op any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOp", reflect.TypeOf((*MockOpSubmitter)(nil).AddOp), op)
+}
+
+// MockLeaderResolver is a mock of LeaderResolver interface.
+type MockLeaderResolver struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaderResolverMockRecorder
+	isgomock struct{}
+}
+
+// MockLeaderResolverMockRecorder is the mock recorder for MockLeaderResolver.
+type MockLeaderResolverMockRecorder struct {
+	mock *MockLeaderResolver
+}
+
+// NewMockLeaderResolver creates a new mock instance.
+func NewMockLeaderResolver(ctrl *gomock.Controller) *MockLeaderResolver {
+	mock := &MockLeaderResolver{ctrl: ctrl}
+	mock.recorder = &MockLeaderResolverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaderResolver) EXPECT() *MockLeaderResolverMockRecorder {
+	return m.recorder
+}
+
+// LeaderAddr mocks base method.
+func (m *MockLeaderResolver) LeaderAddr(ctx context.Context) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaderAddr", ctx)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LeaderAddr indicates an expected call of LeaderAddr.
+func (mr *MockLeaderResolverMockRecorder) LeaderAddr(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaderAddr", reflect.TypeOf((*MockLeaderResolver)(nil).LeaderAddr), ctx)
+}
@@ -0,0 +1,83 @@
+package clientapi
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	clientv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/client/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type serv struct {
+	clientv1.UnimplementedClientServiceServer
+
+	grpc *grpc.Server
+
+	// port where to spin a service
+	port string
+
+	ops    OpSubmitter
+	leader LeaderResolver
+
+	// connections to the leaders by address
+	connMU sync.Mutex
+	conns  map[string]*grpc.ClientConn
+}
+
+func New(port string, ops OpSubmitter, leader LeaderResolver) Server {
+	return &serv{
+		port:   port,
+		ops:    ops,
+		leader: leader,
+		conns:  make(map[string]*grpc.ClientConn),
+	}
+}
+
+// Blocking function
+func (s *serv) Start() {
+	lis, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%v", s.port))
+	if err != nil {
+		panic(err)
+	}
+	grpcServ := grpc.NewServer()
+	s.grpc = grpcServ
+
+	clientv1.RegisterClientServiceServer(grpcServ, s)
+
+	if err := grpcServ.Serve(lis); err != nil {
+		panic(err)
+	}
+}
+
+// Graceful stop
+func (s *serv) Stop() {
+	if s.grpc != nil {
+		s.grpc.GracefulStop()
+		s.grpc = nil
+	}
+
+	s.connMU.Lock()
+	defer s.connMU.Unlock()
+	for addr, conn := range s.conns {
+		conn.Close()
+		delete(s.conns, addr)
+	}
+}
+
+func (s *serv) leaderClient(addr string) (clientv1.ClientServiceClient, error) {
+	s.connMU.Lock()
+	defer s.connMU.Unlock()
+
+	conn, ok := s.conns[addr]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return nil, err
+		}
+		s.conns[addr] = conn
+	}
+	return clientv1.NewClientServiceClient(conn), nil
+}
@@ -0,0 +1,107 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// follows the committed vector in etcd
+// and wakes up requests whose offsets are committed
+type commitWatcher struct {
+	mu        sync.Mutex
+	committed maroon.OffsetVector
+	waiters   map[maroon.OffsetKey][]chan struct{}
+}
+
+func newCommitWatcher() *commitWatcher {
+	return &commitWatcher{
+		committed: make(maroon.OffsetVector),
+		waiters:   make(map[maroon.OffsetKey][]chan struct{}),
+	}
+}
+
+func (w *commitWatcher) run(ctx context.Context, watchCh clientv3.WatchChan) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case resp, ok := <-watchCh:
+			if !ok {
+				logger.Errorf(logger.Application, "etcd vector watch channel is closed")
+				return
+			}
+			for _, ev := range resp.Events {
+				if ev.Type != clientv3.EventTypePut {
+					continue
+				}
+				rec, err := maroon.DecodeCommittedRecord(ev.Kv.Value)
+				if err != nil {
+					logger.Errorf(logger.Application, "skip committed vector: %v", err)
+					continue
+				}
+				w.update(rec.Vector)
+			}
+		}
+	}
+}
+
+func (w *commitWatcher) update(vector maroon.OffsetVector) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.committed = w.committed.Merge(vector)
+	for key, chs := range w.waiters {
+		if key.Offset < w.committed[key.RangeIndex] {
+			for _, ch := range chs {
+				close(ch)
+			}
+			delete(w.waiters, key)
+		}
+	}
+}
+
+// offsets of the range below it are committed
+func (w *commitWatcher) offset(rangeIndex uint64) uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.committed[rangeIndex]
+}
+
+func (w *commitWatcher) isCommitted(key maroon.OffsetKey) bool {
+	return key.Offset < w.offset(key.RangeIndex)
+}
+
+// blocks until the key is committed
+func (w *commitWatcher) wait(ctx context.Context, key maroon.OffsetKey) error {
+	w.mu.Lock()
+	if key.Offset < w.committed[key.RangeIndex] {
+		w.mu.Unlock()
+		return nil
+	}
+	ch := make(chan struct{})
+	w.waiters[key] = append(w.waiters[key], ch)
+	w.mu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		chs := w.waiters[key]
+		for i, c := range chs {
+			if c == ch {
+				w.waiters[key] = append(chs[:i], chs[i+1:]...)
+				break
+			}
+		}
+		if len(w.waiters[key]) == 0 {
+			delete(w.waiters, key)
+		}
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// lease ttl of the key ranges
+const rangeTTLSeconds = 30
+
+var ErrNotDelivered = errors.New("transaction is not delivered to any maroon node")
+
+type gateway struct {
+	cli     ETCD
+	nodes   []gatewayv1.GatewayServiceClient
+	ranges  *rangeHolder
+	commits *commitWatcher
+
+	// offsets that have to be filled with no-ops, see fill
+	holesMu sync.Mutex
+	holes   []maroon.OffsetKey
+	holesCh chan struct{}
+}
+
+// nodes - gateway api clients of all maroon nodes
+func New(gatewayID string, nodes []gatewayv1.GatewayServiceClient, cli ETCD) Gateway {
+	g := &gateway{
+		cli:     cli,
+		nodes:   nodes,
+		commits: newCommitWatcher(),
+		holesCh: make(chan struct{}, 1),
+	}
+	g.ranges = newRangeHolder(gatewayID, rangeTTLSeconds, failoverClient{nodes: nodes}, g.reclaimed)
+	return g
+}
+
+func (g *gateway) Run(ctx context.Context) error {
+	resp, err := g.cli.Get(ctx, maroon.VectorKey)
+	if err != nil {
+		return fmt.Errorf("failed to get committed vector: %w", err)
+	}
+	for _, kv := range resp.Kvs {
+		rec, err := maroon.DecodeCommittedRecord(kv.Value)
+		if err != nil {
+			return err
+		}
+		g.commits.update(rec.Vector)
+	}
+	watchCh := g.cli.Watch(ctx, maroon.VectorKey, clientv3.WithRev(resp.Header.GetRevision()+1))
+	go g.commits.run(ctx, watchCh)
+	go g.fillHoles(ctx)
+
+	ticker := time.NewTicker(rangeTTLSeconds * time.Second / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := g.ranges.keepAlive(ctx); err != nil {
+				logger.Errorf(logger.Network, "%v", err)
+			}
+		}
+	}
+}
+
+func (g *gateway) Submit(ctx context.Context, payload []byte) (maroon.OffsetKey, error) {
+	key, err := g.ranges.nextKey(ctx)
+	if err != nil {
+		return maroon.OffsetKey{}, err
+	}
+	req := &gatewayv1.SubmitTxRequest{
+		RangeIndex: key.RangeIndex,
+		Offset:     key.Offset,
+		Payload:    payload,
+	}
+	if err := g.send(ctx, req); err != nil {
+		// the offset is taken already, later ones of the range wait for it
+		g.fill(key)
+		return key, err
+	}
+	if err := g.commits.wait(ctx, key); err != nil {
+		return key, fmt.Errorf("tx (%d, %d) is not committed: %w", key.RangeIndex, key.Offset, err)
+	}
+	return key, nil
+}
+
+// sends the payload to all nodes, so it's there even if some of them are down
+// one node is enough, it gossips the payload to the rest
+func (g *gateway) send(ctx context.Context, req *gatewayv1.SubmitTxRequest) error {
+	errCh := make(chan error, len(g.nodes))
+	for _, n := range g.nodes {
+		go func() {
+			_, err := n.SubmitTx(ctx, req)
+			errCh <- err
+		}()
+	}
+
+	var errs []error
+	for range g.nodes {
+		err := <-errCh
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(append([]error{ErrNotDelivered}, errs...)...)
+}
@@ -0,0 +1,161 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/gatewayapi"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type txSink struct {
+	txs   chan maroon.OffsetKey
+	noops chan maroon.OffsetKey
+}
+
+func (s txSink) AddTx(key maroon.OffsetKey, _ []byte) {
+	s.txs <- key
+}
+
+func (s txSink) AddNoOp(key maroon.OffsetKey) {
+	s.noops <- key
+}
+
+// fails the first submits
+type flakyNode struct {
+	gatewayv1.GatewayServiceClient
+
+	mu       sync.Mutex
+	failures int
+}
+
+func (n *flakyNode) SubmitTx(ctx context.Context, in *gatewayv1.SubmitTxRequest, opts ...grpc.CallOption) (*gatewayv1.SubmitTxResponse, error) {
+	n.mu.Lock()
+	fail := n.failures > 0
+	n.failures--
+	n.mu.Unlock()
+	if fail {
+		return nil, errors.New("node is not reachable")
+	}
+	return n.GatewayServiceClient.SubmitTx(ctx, in, opts...)
+}
+
+func TestSubmitWaitsForCommit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	etcd := etcdmock.New()
+	sink := txSink{txs: make(chan maroon.OffsetKey, 10), noops: make(chan maroon.OffsetKey, 10)}
+	api := gatewayapi.New("8097", keyrange.NewAllocator(etcd, maroon.RangesKey), sink)
+	go api.Start()
+	defer api.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := grpc.NewClient("localhost:8097", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	gw := New("gw-1", []gatewayv1.GatewayServiceClient{gatewayv1.NewGatewayServiceClient(conn)}, etcd)
+	go gw.Run(ctx)
+
+	type result struct {
+		key maroon.OffsetKey
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		key, err := gw.Submit(ctx, []byte("tx"))
+		resCh <- result{key, err}
+	}()
+
+	key := <-sink.txs
+	require.Equal(t, maroon.OffsetKey{RangeIndex: 0, Offset: 0}, key)
+
+	select {
+	case <-resCh:
+		t.Fatal("tx is not committed yet")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	rec, err := maroon.CommittedRecord{Seq: 1, Vector: maroon.OffsetVector{0: 1}}.Encode()
+	require.NoError(t, err)
+	_, err = etcd.Put(ctx, maroon.VectorKey, string(rec))
+	require.NoError(t, err)
+
+	select {
+	case res := <-resCh:
+		require.NoError(t, res.err)
+		require.Equal(t, key, res.key)
+	case <-time.After(time.Second):
+		t.Fatal("tx is not acked after commit")
+	}
+}
+
+func TestCommitWatcherTimeout(t *testing.T) {
+	w := newCommitWatcher()
+	w.update(maroon.OffsetVector{1: 5})
+
+	require.NoError(t, w.wait(context.Background(), maroon.OffsetKey{RangeIndex: 1, Offset: 4}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, w.wait(ctx, maroon.OffsetKey{RangeIndex: 1, Offset: 5}), context.DeadlineExceeded)
+	require.Empty(t, w.waiters)
+}
+
+func TestFailedSendIsFilledWithNoOp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	etcd := etcdmock.New()
+	sink := txSink{txs: make(chan maroon.OffsetKey, 10), noops: make(chan maroon.OffsetKey, 10)}
+	api := gatewayapi.New("8130", keyrange.NewAllocator(etcd, maroon.RangesKey), sink)
+	go api.Start()
+	defer api.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	conn, err := grpc.NewClient("localhost:8130", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	node := &flakyNode{GatewayServiceClient: gatewayv1.NewGatewayServiceClient(conn), failures: 1}
+	gw := New("gw-1", []gatewayv1.GatewayServiceClient{node}, etcd)
+	go gw.Run(ctx)
+
+	_, err = gw.Submit(ctx, []byte("tx"))
+	require.ErrorIs(t, err, ErrNotDelivered)
+
+	select {
+	case key := <-sink.noops:
+		require.Equal(t, maroon.OffsetKey{RangeIndex: 0, Offset: 0}, key)
+	case <-time.After(time.Second):
+		t.Fatal("failed offset is not filled")
+	}
+	require.Empty(t, sink.txs)
+}
+
+func TestReclaimedRangeIsFilled(t *testing.T) {
+	g := New("gw-1", nil, etcdmock.New()).(*gateway)
+	g.commits.update(maroon.OffsetVector{3: 2})
+
+	// the previous owner reserved up to 5 but only 0 and 1 are committed
+	g.reclaimed(keyrange.Range{Index: 3, Offset: 5})
+	require.Equal(t, []maroon.OffsetKey{
+		{RangeIndex: 3, Offset: 2},
+		{RangeIndex: 3, Offset: 3},
+		{RangeIndex: 3, Offset: 4},
+	}, g.holes)
+
+	g.holes = nil
+	g.reclaimed(keyrange.Range{Index: 4, Offset: 0})
+	require.Empty(t, g.holes)
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"time"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+const (
+	minFillBackoff = 100 * time.Millisecond
+	maxFillBackoff = 5 * time.Second
+)
+
+// committed vector only moves when there are no gaps in a range
+// so an offset that is never delivered blocks every later offset of the range
+// such offsets are filled with no-ops:
+//   - the ones whose send failed
+//   - the ones the previous owner of a reclaimed range reserved but didn't use
+//
+// TODO: a node that got the real payload before the no-op keeps the payload, the one that got only the no-op skips it,
+// so a send that failed on the gateway side but reached some node can apply differently
+func (g *gateway) fill(keys ...maroon.OffsetKey) {
+	if len(keys) == 0 {
+		return
+	}
+	g.holesMu.Lock()
+	g.holes = append(g.holes, keys...)
+	g.holesMu.Unlock()
+
+	select {
+	case g.holesCh <- struct{}{}:
+	default:
+	}
+}
+
+// offsets of the range below its start could've been reserved by the previous owner
+// only the committed ones are known to be used
+func (g *gateway) reclaimed(r keyrange.Range) {
+	from := g.commits.offset(r.Index)
+
+	var keys []maroon.OffsetKey
+	for offset := from; offset < r.Offset; offset++ {
+		keys = append(keys, maroon.OffsetKey{RangeIndex: r.Index, Offset: offset})
+	}
+	if len(keys) > 0 {
+		logger.Infof(logger.Network, "range %d is reclaimed, fill offsets [%d, %d) with no-ops", r.Index, from, r.Offset)
+	}
+	g.fill(keys...)
+}
+
+// blocking, until ctx is done
+func (g *gateway) fillHoles(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.holesCh:
+		}
+
+		g.holesMu.Lock()
+		holes := g.holes
+		g.holes = nil
+		g.holesMu.Unlock()
+
+		for _, key := range holes {
+			if !g.fillHole(ctx, key) {
+				return
+			}
+		}
+	}
+}
+
+// retries until the no-op is delivered
+// false if ctx is done before that
+func (g *gateway) fillHole(ctx context.Context, key maroon.OffsetKey) bool {
+	req := &gatewayv1.SubmitTxRequest{
+		RangeIndex: key.RangeIndex,
+		Offset:     key.Offset,
+		Noop:       true,
+	}
+	backoff := minFillBackoff
+	for {
+		if g.commits.isCommitted(key) {
+			return true
+		}
+		err := g.send(ctx, req)
+		if err == nil {
+			return true
+		}
+		logger.Warningf(logger.Network, "failed to fill offset (%d, %d), retry in %v: %v", key.RangeIndex, key.Offset, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxFillBackoff)
+	}
+}
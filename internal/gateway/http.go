@@ -0,0 +1,65 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+// the biggest request body that is accepted
+const maxPayloadSize = 1 << 20
+
+type submitResponse struct {
+	ID         uint64 `json:"id"`
+	RangeIndex uint64 `json:"rangeIndex"`
+	Offset     uint64 `json:"offset"`
+}
+
+// client facing api
+//
+//	POST /v1/requests - body is the transaction payload
+//	responds after the transaction is committed
+func NewHandler(g Gateway, commitTimeout time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/requests", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), commitTimeout)
+		defer cancel()
+
+		key, err := g.Submit(ctx, payload)
+		if err != nil {
+			logger.Errorf(logger.Network, "request failed: %v", err)
+			code := http.StatusServiceUnavailable
+			if errors.Is(err, context.DeadlineExceeded) {
+				code = http.StatusGatewayTimeout
+			}
+			http.Error(w, err.Error(), code)
+			return
+		}
+
+		id, err := keyrange.Range{Index: key.RangeIndex}.ID(key.Offset)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(submitResponse{
+			ID:         id,
+			RangeIndex: key.RangeIndex,
+			Offset:     key.Offset,
+		})
+	})
+	return mux
+}
@@ -0,0 +1,22 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type Gateway interface {
+	// Blocking function
+	// follows committed offsets and keeps the key range lease alive
+	Run(ctx context.Context) error
+	// attaches (rangeIndex, offset) to the payload, sends it to maroon nodes
+	// and waits until the offset is committed
+	Submit(ctx context.Context, payload []byte) (maroon.OffsetKey, error)
+}
+
+type ETCD interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+}
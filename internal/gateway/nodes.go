@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"google.golang.org/grpc"
+)
+
+// any maroon node can give out key ranges, they all go to etcd
+// so the requests go to the first node that answers
+type failoverClient struct {
+	nodes []gatewayv1.GatewayServiceClient
+}
+
+var _ gatewayv1.GatewayServiceClient = failoverClient{}
+
+func (c failoverClient) AcquireRange(ctx context.Context, in *gatewayv1.AcquireRangeRequest, opts ...grpc.CallOption) (*gatewayv1.AcquireRangeResponse, error) {
+	return firstOf(c.nodes, func(n gatewayv1.GatewayServiceClient) (*gatewayv1.AcquireRangeResponse, error) {
+		return n.AcquireRange(ctx, in, opts...)
+	})
+}
+
+func (c failoverClient) NextRange(ctx context.Context, in *gatewayv1.NextRangeRequest, opts ...grpc.CallOption) (*gatewayv1.NextRangeResponse, error) {
+	return firstOf(c.nodes, func(n gatewayv1.GatewayServiceClient) (*gatewayv1.NextRangeResponse, error) {
+		return n.NextRange(ctx, in, opts...)
+	})
+}
+
+func (c failoverClient) ReserveOffsets(ctx context.Context, in *gatewayv1.ReserveOffsetsRequest, opts ...grpc.CallOption) (*gatewayv1.ReserveOffsetsResponse, error) {
+	return firstOf(c.nodes, func(n gatewayv1.GatewayServiceClient) (*gatewayv1.ReserveOffsetsResponse, error) {
+		return n.ReserveOffsets(ctx, in, opts...)
+	})
+}
+
+func (c failoverClient) KeepAlive(ctx context.Context, in *gatewayv1.KeepAliveRequest, opts ...grpc.CallOption) (*gatewayv1.KeepAliveResponse, error) {
+	return firstOf(c.nodes, func(n gatewayv1.GatewayServiceClient) (*gatewayv1.KeepAliveResponse, error) {
+		return n.KeepAlive(ctx, in, opts...)
+	})
+}
+
+// payloads go to all nodes in parallel, see submit
+func (c failoverClient) SubmitTx(ctx context.Context, in *gatewayv1.SubmitTxRequest, opts ...grpc.CallOption) (*gatewayv1.SubmitTxResponse, error) {
+	return firstOf(c.nodes, func(n gatewayv1.GatewayServiceClient) (*gatewayv1.SubmitTxResponse, error) {
+		return n.SubmitTx(ctx, in, opts...)
+	})
+}
+
+func firstOf[T any](nodes []gatewayv1.GatewayServiceClient, call func(gatewayv1.GatewayServiceClient) (T, error)) (T, error) {
+	var errs []error
+	for _, n := range nodes {
+		resp, err := call(n)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+	}
+	var zero T
+	if len(errs) == 0 {
+		return zero, errors.New("no maroon nodes")
+	}
+	return zero, errors.Join(errs...)
+}
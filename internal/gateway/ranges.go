@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+const (
+	// offsets are reserved in batches, so not every request goes to etcd
+	reserveBatch = 1000
+	// when less offsets left in the range the next one is requested
+	nextRangeThreshold = 10 * reserveBatch
+)
+
+// gives out unique (rangeIndex, offset) pairs
+// see doc/key-range.md
+type rangeHolder struct {
+	mu sync.Mutex
+
+	gatewayID  string
+	ttlSeconds int64
+	api        gatewayv1.GatewayServiceClient
+
+	lease int64
+	// zero value means there is no range yet
+	current *keyrange.Range
+	// requested in advance, when current is close to the end
+	spare *keyrange.Range
+	// next offset to give out in the current range
+	next uint64
+	// offsets below are reserved in etcd
+	reserved uint64
+
+	// range that had an owner before, it could've left unused offsets below its start
+	onReclaimed func(r keyrange.Range)
+}
+
+func newRangeHolder(gatewayID string, ttlSeconds int64, api gatewayv1.GatewayServiceClient, onReclaimed func(r keyrange.Range)) *rangeHolder {
+	return &rangeHolder{
+		gatewayID:   gatewayID,
+		ttlSeconds:  ttlSeconds,
+		api:         api,
+		onReclaimed: onReclaimed,
+	}
+}
+
+func (h *rangeHolder) nextKey(ctx context.Context) (maroon.OffsetKey, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.current == nil {
+		if err := h.acquire(ctx); err != nil {
+			return maroon.OffsetKey{}, err
+		}
+	}
+
+	if h.next >= keyrange.RangeSize {
+		if h.spare == nil {
+			if err := h.requestSpare(ctx); err != nil {
+				return maroon.OffsetKey{}, err
+			}
+		}
+		h.use(*h.spare)
+		h.spare = nil
+	}
+
+	if h.next >= h.reserved {
+		upTo := min(h.next+reserveBatch, keyrange.RangeSize)
+		_, err := h.api.ReserveOffsets(ctx, &gatewayv1.ReserveOffsetsRequest{
+			GatewayId:  h.gatewayID,
+			LeaseId:    h.lease,
+			RangeIndex: h.current.Index,
+			UpTo:       upTo,
+		})
+		if err != nil {
+			return maroon.OffsetKey{}, fmt.Errorf("failed to reserve offsets: %w", err)
+		}
+		h.reserved = upTo
+	}
+
+	key := maroon.OffsetKey{RangeIndex: h.current.Index, Offset: h.next}
+	h.next++
+
+	if keyrange.RangeSize-h.next < nextRangeThreshold && h.spare == nil {
+		if err := h.requestSpare(ctx); err != nil {
+			// not critical yet, will try again with the next request
+			logger.Warningf(logger.Network, "failed to request next range: %v", err)
+		}
+	}
+	return key, nil
+}
+
+func (h *rangeHolder) keepAlive(ctx context.Context) error {
+	h.mu.Lock()
+	lease := h.lease
+	h.mu.Unlock()
+	if lease == 0 {
+		return nil
+	}
+
+	_, err := h.api.KeepAlive(ctx, &gatewayv1.KeepAliveRequest{LeaseId: lease})
+	if err != nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.lease == lease {
+			// ranges could've been given to somebody else already
+			// start from scratch with the next request
+			h.current, h.spare, h.lease = nil, nil, 0
+		}
+		return fmt.Errorf("lease %d is lost: %w", lease, err)
+	}
+	return nil
+}
+
+// should be called under mu
+func (h *rangeHolder) acquire(ctx context.Context) error {
+	resp, err := h.api.AcquireRange(ctx, &gatewayv1.AcquireRangeRequest{
+		GatewayId:  h.gatewayID,
+		TtlSeconds: h.ttlSeconds,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to acquire range: %w", err)
+	}
+	h.lease = resp.LeaseId
+	h.use(rangeFromProto(resp.Range))
+	logger.Infof(logger.Network, "got range %d from offset %d, lease %d", h.current.Index, h.next, h.lease)
+	return nil
+}
+
+// should be called under mu
+func (h *rangeHolder) requestSpare(ctx context.Context) error {
+	resp, err := h.api.NextRange(ctx, &gatewayv1.NextRangeRequest{
+		GatewayId: h.gatewayID,
+		LeaseId:   h.lease,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get next range: %w", err)
+	}
+	r := rangeFromProto(resp.Range)
+	h.spare = &r
+	logger.Infof(logger.Network, "got spare range %d from offset %d", r.Index, r.Offset)
+	return nil
+}
+
+// should be called under mu
+func (h *rangeHolder) use(r keyrange.Range) {
+	h.current = &r
+	h.next = r.Offset
+	// whatever is reserved in etcd could've been used by the previous owner
+	h.reserved = r.Offset
+	if r.Offset > 0 && h.onReclaimed != nil {
+		h.onReclaimed(r)
+	}
+}
+
+func rangeFromProto(r *gatewayv1.KeyRange) keyrange.Range {
+	return keyrange.Range{
+		Index:  r.GetIndex(),
+		Offset: r.GetOffset(),
+	}
+}
@@ -0,0 +1,97 @@
+package gatewayapi
+
+import (
+	"context"
+	"errors"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// lease ttl if gateway didn't ask for a specific one
+const defaultRangeTTLSeconds = 30
+
+func (s *serv) AcquireRange(ctx context.Context, req *gatewayv1.AcquireRangeRequest) (*gatewayv1.AcquireRangeResponse, error) {
+	if req.GatewayId == "" {
+		return nil, status.Error(codes.InvalidArgument, "gateway_id is required")
+	}
+	ttl := req.TtlSeconds
+	if ttl <= 0 {
+		ttl = defaultRangeTTLSeconds
+	}
+
+	lease, r, err := s.ranges.Acquire(ctx, req.GatewayId, ttl)
+	if err != nil {
+		return nil, rangeError(err)
+	}
+	logger.Infof(logger.Network, "gateway %v got range %d from offset %d", req.GatewayId, r.Index, r.Offset)
+
+	return &gatewayv1.AcquireRangeResponse{
+		LeaseId: int64(lease),
+		Range:   rangeToProto(r),
+	}, nil
+}
+
+func (s *serv) NextRange(ctx context.Context, req *gatewayv1.NextRangeRequest) (*gatewayv1.NextRangeResponse, error) {
+	r, err := s.ranges.Next(ctx, req.GatewayId, clientv3.LeaseID(req.LeaseId))
+	if err != nil {
+		return nil, rangeError(err)
+	}
+	logger.Infof(logger.Network, "gateway %v got next range %d from offset %d", req.GatewayId, r.Index, r.Offset)
+
+	return &gatewayv1.NextRangeResponse{Range: rangeToProto(r)}, nil
+}
+
+func (s *serv) ReserveOffsets(ctx context.Context, req *gatewayv1.ReserveOffsetsRequest) (*gatewayv1.ReserveOffsetsResponse, error) {
+	err := s.ranges.Reserve(ctx, req.GatewayId, clientv3.LeaseID(req.LeaseId), req.RangeIndex, req.UpTo)
+	if err != nil {
+		return nil, rangeError(err)
+	}
+	return &gatewayv1.ReserveOffsetsResponse{}, nil
+}
+
+func (s *serv) KeepAlive(ctx context.Context, req *gatewayv1.KeepAliveRequest) (*gatewayv1.KeepAliveResponse, error) {
+	if err := s.ranges.KeepAlive(ctx, clientv3.LeaseID(req.LeaseId)); err != nil {
+		// most likely the lease is expired, gateway has to start from scratch
+		return nil, status.Errorf(codes.NotFound, "failed to keep lease alive: %v", err)
+	}
+	return &gatewayv1.KeepAliveResponse{}, nil
+}
+
+func (s *serv) SubmitTx(_ context.Context, req *gatewayv1.SubmitTxRequest) (*gatewayv1.SubmitTxResponse, error) {
+	if s.txs == nil {
+		return nil, status.Error(codes.Unavailable, "node doesn't run the offset vector protocol")
+	}
+
+	key := maroon.OffsetKey{RangeIndex: req.RangeIndex, Offset: req.Offset}
+	if req.Noop {
+		s.txs.AddNoOp(key)
+	} else {
+		s.txs.AddTx(key, req.Payload)
+	}
+	return &gatewayv1.SubmitTxResponse{}, nil
+}
+
+func rangeToProto(r keyrange.Range) *gatewayv1.KeyRange {
+	return &gatewayv1.KeyRange{
+		Index:  r.Index,
+		Offset: r.Offset,
+	}
+}
+
+func rangeError(err error) error {
+	switch {
+	case errors.Is(err, keyrange.ErrNotOwner):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, keyrange.ErrNoRanges):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, keyrange.ErrContention):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -0,0 +1,31 @@
+package gatewayapi
+
+import (
+	"context"
+
+	"github.com/akantsevoi/test-environment/internal/maroon"
+	"github.com/akantsevoi/test-environment/pkg/keyrange"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// node side API for the communication gateways
+type Server interface {
+	// blocking
+	Start()
+	Stop()
+}
+
+type RangeAllocator interface {
+	Acquire(ctx context.Context, gatewayID string, ttlSeconds int64) (clientv3.LeaseID, keyrange.Range, error)
+	Next(ctx context.Context, gatewayID string, lease clientv3.LeaseID) (keyrange.Range, error)
+	Reserve(ctx context.Context, gatewayID string, lease clientv3.LeaseID, index, upTo uint64) error
+	KeepAlive(ctx context.Context, lease clientv3.LeaseID) error
+}
+
+// receives client requests from the gateways
+// maroon.OffsetApplication
+type TxSink interface {
+	AddTx(key maroon.OffsetKey, payload []byte)
+	// fills an offset the gateway never used
+	AddNoOp(key maroon.OffsetKey)
+}
@@ -0,0 +1,199 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: internal/gatewayapi/interface.go
+//
+// Generated by this command:
+//
+//	mockgen -source=internal/gatewayapi/interface.go -destination=internal/gatewayapi/mocks/interface_mock.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	maroon "github.com/akantsevoi/test-environment/internal/maroon"
+	keyrange "github.com/akantsevoi/test-environment/pkg/keyrange"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockServer is a mock of Server interface.
+type MockServer struct {
+	ctrl     *gomock.Controller
+	recorder *MockServerMockRecorder
+	isgomock struct{}
+}
+
+// MockServerMockRecorder is the mock recorder for MockServer.
+type MockServerMockRecorder struct {
+	mock *MockServer
+}
+
+// NewMockServer creates a new mock instance.
+func NewMockServer(ctrl *gomock.Controller) *MockServer {
+	mock := &MockServer{ctrl: ctrl}
+	mock.recorder = &MockServerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockServer) EXPECT() *MockServerMockRecorder {
+	return m.recorder
+}
+
+// Start mocks base method.
+func (m *MockServer) Start() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Start")
+}
+
+// Start indicates an expected call of Start.
+func (mr *MockServerMockRecorder) Start() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Start", reflect.TypeOf((*MockServer)(nil).Start))
+}
+
+// Stop mocks base method.
+func (m *MockServer) Stop() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Stop")
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *MockServerMockRecorder) Stop() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockServer)(nil).Stop))
+}
+
+// MockRangeAllocator is a mock of RangeAllocator interface.
+type MockRangeAllocator struct {
+	ctrl     *gomock.Controller
+	recorder *MockRangeAllocatorMockRecorder
+	isgomock struct{}
+}
+
+// MockRangeAllocatorMockRecorder is the mock recorder for MockRangeAllocator.
+type MockRangeAllocatorMockRecorder struct {
+	mock *MockRangeAllocator
+}
+
+// NewMockRangeAllocator creates a new mock instance.
+func NewMockRangeAllocator(ctrl *gomock.Controller) *MockRangeAllocator {
+	mock := &MockRangeAllocator{ctrl: ctrl}
+	mock.recorder = &MockRangeAllocatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRangeAllocator) EXPECT() *MockRangeAllocatorMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockRangeAllocator) Acquire(ctx context.Context, gatewayID string, ttlSeconds int64) (clientv3.LeaseID, keyrange.Range, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx, gatewayID, ttlSeconds)
+	ret0, _ := ret[0].(clientv3.LeaseID)
+	ret1, _ := ret[1].(keyrange.Range)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockRangeAllocatorMockRecorder) Acquire(ctx, gatewayID, ttlSeconds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockRangeAllocator)(nil).Acquire), ctx, gatewayID, ttlSeconds)
+}
+
+// KeepAlive mocks base method.
+func (m *MockRangeAllocator) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeepAlive", ctx, lease)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// KeepAlive indicates an expected call of KeepAlive.
+func (mr *MockRangeAllocatorMockRecorder) KeepAlive(ctx, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeepAlive", reflect.TypeOf((*MockRangeAllocator)(nil).KeepAlive), ctx, lease)
+}
+
+// Next mocks base method.
+func (m *MockRangeAllocator) Next(ctx context.Context, gatewayID string, lease clientv3.LeaseID) (keyrange.Range, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Next", ctx, gatewayID, lease)
+	ret0, _ := ret[0].(keyrange.Range)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Next indicates an expected call of Next.
+func (mr *MockRangeAllocatorMockRecorder) Next(ctx, gatewayID, lease any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Next", reflect.TypeOf((*MockRangeAllocator)(nil).Next), ctx, gatewayID, lease)
+}
+
+// Reserve mocks base method.
+func (m *MockRangeAllocator) Reserve(ctx context.Context, gatewayID string, lease clientv3.LeaseID, index, upTo uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, gatewayID, lease, index, upTo)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockRangeAllocatorMockRecorder) Reserve(ctx, gatewayID, lease, index, upTo any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockRangeAllocator)(nil).Reserve), ctx, gatewayID, lease, index, upTo)
+}
+
+// MockTxSink is a mock of TxSink interface.
+type MockTxSink struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxSinkMockRecorder
+	isgomock struct{}
+}
+
+// MockTxSinkMockRecorder is the mock recorder for MockTxSink.
+type MockTxSinkMockRecorder struct {
+	mock *MockTxSink
+}
+
+// NewMockTxSink creates a new mock instance.
+func NewMockTxSink(ctrl *gomock.Controller) *MockTxSink {
+	mock := &MockTxSink{ctrl: ctrl}
+	mock.recorder = &MockTxSinkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTxSink) EXPECT() *MockTxSinkMockRecorder {
+	return m.recorder
+}
+
+// AddNoOp mocks base method.
+func (m *MockTxSink) AddNoOp(key maroon.OffsetKey) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddNoOp", key)
+}
+
+// AddNoOp indicates an expected call of AddNoOp.
+func (mr *MockTxSinkMockRecorder) AddNoOp(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNoOp", reflect.TypeOf((*MockTxSink)(nil).AddNoOp), key)
+}
+
+// AddTx mocks base method.
+func (m *MockTxSink) AddTx(key maroon.OffsetKey, payload []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddTx", key, payload)
+}
+
+// AddTx indicates an expected call of AddTx.
+func (mr *MockTxSinkMockRecorder) AddTx(key, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTx", reflect.TypeOf((*MockTxSink)(nil).AddTx), key, payload)
+}
@@ -0,0 +1,55 @@
+package gatewayapi
+
+import (
+	"fmt"
+	"net"
+
+	gatewayv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/gateway/v1"
+	"google.golang.org/grpc"
+)
+
+type serv struct {
+	gatewayv1.UnimplementedGatewayServiceServer
+
+	grpc *grpc.Server
+
+	// port where to spin a service
+	port string
+
+	ranges RangeAllocator
+	// nil if the node doesn't run the offset vector protocol
+	txs TxSink
+}
+
+func New(port string, ranges RangeAllocator, txs TxSink) Server {
+	return &serv{
+		port:   port,
+		ranges: ranges,
+		txs:    txs,
+	}
+}
+
+// Blocking function
+func (s *serv) Start() {
+	lis, err := net.Listen("tcp", fmt.Sprintf("0.0.0.0:%v", s.port))
+	if err != nil {
+		panic(err)
+	}
+	grpcServ := grpc.NewServer()
+	s.grpc = grpcServ
+
+	gatewayv1.RegisterGatewayServiceServer(grpcServ, s)
+
+	if err := grpcServ.Serve(lis); err != nil {
+		panic(err)
+	}
+}
+
+// Graceful stop
+func (s *serv) Stop() {
+	if s.grpc == nil {
+		return
+	}
+	s.grpc.GracefulStop()
+	s.grpc = nil
+}
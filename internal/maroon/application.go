@@ -2,9 +2,13 @@ package maroon
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"math"
+	"math/rand/v2"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/akantsevoi/test-environment/internal/p2p"
 	"github.com/akantsevoi/test-environment/pkg/logger"
@@ -19,64 +23,191 @@ type application struct {
 type data struct {
 	// all the operations that were confirmed by the followers
 	// a slice because they have global order now
+	// operations covered by snapshots are dropped from the head
 	confirmedOps []Operation
+	// global position of the operation by its hash
+	// confirmedOps[pos-compactedOps]
+	confirmedIdx map[string]int
+	// how many operations were dropped from confirmedOps
+	compactedOps int
 
-	//
+	// leader side
+	// operations distributed to the followers in the ack order, they go to the next blocks
 	ackedHashes []string
+	// when the operation was acked, it's not there for the ones recovered from the wal
+	ackedAt map[string]time.Time
 
 	// txs that were created and sent to the followers but not ack-ed yet
 	inFlyOPs map[string]Operation
 
+	// follower side
+	// txs received from the leader that are not in any block yet
+	receivedOps map[string]Operation
+
+	// envelopes of the operations this node can't encode back, see keepRaw
+	rawOps map[string][]byte
+
+	// leader side
+	// clients that wait for the status changes of the operation
+	opWatchers map[string][]chan OpStatus
+
 	// TODO: get rid of locks
 	opMU *sync.Mutex
 
+	// number of the next block
 	batchCounter int64
+	// hash of the last block record put to etcd
+	prevBlockHash string
+	// merkle root of the last block
+	prevBlockRoot string
+
+	// results of the recently applied client requests
+	dedup *dedupTable
+
+	// latest snapshot, encoded
+	snapshot      []byte
+	snapshotBlock int64
+	// amount of confirmed operations at the moment of the latest snapshot
+	snapshotOps int
 
 	isLeader bool
+	// number of the last block the leader knows to be in etcd
+	// new blocks are sealed only after it's applied
+	chainHead int64
+	// create revision of the leader key, guards leader writes
+	leaderRev int64
+	demotedCh chan int64
 }
 
 type deps struct {
 	cli      ETCD
 	p2pDistr DistTransport
+	// nil if the state is only in memory
+	wal WAL
+
+	sm            StateMachine
+	snapshotEvery int64
+	seal          SealPolicy
 }
 
-func New(cli ETCD, p2pDistr DistTransport) *application {
-	return &application{
+func New(cli ETCD, p2pDistr DistTransport, opts ...Option) *application {
+	a := &application{
 		data: data{
-			inFlyOPs: make(map[string]Operation),
-			opMU:     &sync.Mutex{},
+			inFlyOPs:     make(map[string]Operation),
+			receivedOps:  make(map[string]Operation),
+			rawOps:       make(map[string][]byte),
+			confirmedIdx: make(map[string]int),
+			ackedAt:      make(map[string]time.Time),
+			opWatchers:   make(map[string][]chan OpStatus),
+			demotedCh:    make(chan int64, 1),
+			chainHead:    -1,
+			dedup:        newDedupTable(defaultDedupWindow),
+			opMU:         &sync.Mutex{},
 		},
 		deps: deps{
 			cli:      cli,
 			p2pDistr: p2pDistr,
+			sm:       DefaultRegistry(),
+			seal:     DefaultSealPolicy(),
 		},
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
-func (a *application) Run(isLeaderCh <-chan bool, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
+func (a *application) Run(isLeaderCh <-chan Leadership, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	blocksCh := make(chan Block, maxPendingBlocks)
+	go a.catchUp(ctx, blocksCh)
+	// the loop doesn't wait for the catch up, the latest block that didn't fit waits there
+	// catch up fetches the ones before it from etcd anyway
+	var overflow Block
+	var overflowCh chan<- Block
+	deliver := func(block Block) {
+		if overflowCh == nil {
+			select {
+			case blocksCh <- block:
+				return
+			default:
+			}
+		} else if block.Number <= overflow.Number {
+			return
+		}
+		overflow, overflowCh = block, blocksCh
+	}
+
+	// partial blocks are checked twice per linger
+	// so an operation waits for at most 1.5 of it
+	var lingerCh <-chan time.Time
+	if a.seal.Linger > 0 {
+		ticker := time.NewTicker(max(a.seal.Linger/2, time.Millisecond))
+		defer ticker.Stop()
+		lingerCh = ticker.C
+	}
 
 	for {
 		select {
 		case <-stopCh:
 			return
-		case isL := <-isLeaderCh:
-			a.isLeader = isL
+		case overflowCh <- overflow:
+			overflowCh = nil
+		case <-lingerCh:
+			a.opMU.Lock()
+			a.sealBlockIfCan(a.cli)
+			a.opMU.Unlock()
+		case l := <-isLeaderCh:
+			a.opMU.Lock()
+			promoted := l.IsLeader && !a.isLeader
+			a.isLeader = l.IsLeader
+			a.leaderRev = l.Revision
+			if promoted {
+				// nothing is sealed until the node knows where the chain ends
+				a.chainHead = math.MaxInt64
+			}
+			a.opMU.Unlock()
+
+			if promoted {
+				if err := a.syncChain(ctx, deliver); err != nil {
+					a.opMU.Lock()
+					demote(&a.isLeader, a.leaderRev, a.demotedCh, err)
+					a.opMU.Unlock()
+					continue
+				}
+				go a.recoverPending(ctx, l.Revision)
+			}
 		case confirmation := <-distributedTxCh:
-			if !a.isLeader {
+			a.opMU.Lock()
+			isLeader := a.isLeader
+			a.opMU.Unlock()
+			if !isLeader {
 				continue
 			}
 			logger.Infof(logger.Application, "tx %v confirmed", confirmation.ID)
 			a.issueBlockIfCan(a.cli, confirmation)
 
-		case newEvent := <-etcdWatchCh:
-			if a.isLeader {
-				// leader already knows about it
+		case newEvent, ok := <-etcdWatchCh:
+			if !ok {
+				logger.Errorf(logger.Application, "etcd watch channel is closed")
+				etcdWatchCh = nil
 				continue
 			}
-
-			_ = newEvent
-			// TODO: check that all the transactions with that hash are here
-			// and if there are not - start requesting them
+			// leader gets its own blocks back, catch up skips them
+			// but blocks of the previous leader have to be applied
+			for _, ev := range newEvent.Events {
+				if ev.Type != clientv3.EventTypePut {
+					continue
+				}
+				block, err := decodeBlock(ev.Kv.Value)
+				if err != nil {
+					logger.Errorf(logger.Application, "skip block %s: %v", ev.Kv.Key, err)
+					continue
+				}
+				deliver(block)
+			}
 		}
 	}
 }
@@ -88,43 +219,339 @@ func (a *application) issueBlockIfCan(cli ETCD, confirmation p2p.TransactionDist
 		// TODO: just ignore. But would be nice to clarify how you've got them
 		return
 	}
+	if slices.Contains(a.ackedHashes, confirmation.ID) {
+		// operation was distributed once more
+		return
+	}
 
+	if err := a.logRecord(walRecord{Kind: walAck, ID: confirmation.ID}); err != nil {
+		logger.Errorf(logger.Application, "failed to log ack of %v: %v", confirmation.ID, err)
+		return
+	}
 	a.ackedHashes = append(a.ackedHashes, confirmation.ID)
+	a.ackedAt[confirmation.ID] = time.Now()
+	a.notify(OpStatus{ID: confirmation.ID, State: OpDistributed})
 
-	if len(a.ackedHashes) >= 3 {
-		// IMITATION!!!!
-		merkleHash := strings.Join(a.ackedHashes, ",")
-		_, err := cli.Put(context.TODO(), fmt.Sprintf("%s/%d", HashesKey, a.batchCounter), merkleHash)
-		if err != nil {
-			logger.Errorf(logger.Application, "failed to put merkle hash: %v", err)
-			return
+	a.sealBlockIfCan(cli)
+}
+
+// puts acked operations into the next blocks according to the seal policy
+// should be called under opMU
+func (a *application) sealBlockIfCan(cli ETCD) {
+	if !a.isLeader {
+		return
+	}
+	// could've been committed by the previous leader
+	a.ackedHashes = slices.DeleteFunc(a.ackedHashes, func(hash string) bool {
+		_, ok := a.confirmedIdx[hash]
+		if ok {
+			delete(a.ackedAt, hash)
 		}
+		return ok
+	})
+	if len(a.ackedHashes) == 0 {
+		return
+	}
+	if a.batchCounter <= a.chainHead {
+		logger.Infof(logger.Application, "block %d is not applied yet, wait for the catch up", a.chainHead)
+		return
+	}
 
+	for len(a.ackedHashes) > 0 {
+		ops := make([]Operation, 0, len(a.ackedHashes))
 		for _, hash := range a.ackedHashes {
-			op, ok := a.inFlyOPs[hash]
-			if !ok {
-				continue
+			ops = append(ops, a.inFlyOPs[hash])
+		}
+		// acks are in order, the first one waits the longest
+		var waited time.Duration
+		if at, ok := a.ackedAt[a.ackedHashes[0]]; ok {
+			waited = time.Since(at)
+		} else {
+			waited = a.seal.Linger
+		}
+
+		n, reason := a.seal.take(ops, waited)
+		if n == 0 || !a.sealBlock(cli, ops[:n], reason) {
+			return
+		}
+	}
+}
+
+// puts the first len(ops) acked operations into the block
+// returns false if the block isn't there
+// should be called under opMU
+func (a *application) sealBlock(cli ETCD, ops []Operation, reason string) bool {
+	hashes := a.ackedHashes[:len(ops)]
+	block, err := newBlock(a.batchCounter, a.prevBlockHash, hashes)
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to build block: %v", err)
+		return false
+	}
+	block.Term = a.leaderRev
+	record, err := block.Encode()
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to encode block: %v", err)
+		return false
+	}
+	blockHash, err := block.Hash()
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to hash block: %v", err)
+		return false
+	}
+
+	err = fencedCreate(context.TODO(), cli, a.leaderRev, blockKey(block.Number), string(record))
+	if errors.Is(err, ErrFenced) {
+		demote(&a.isLeader, a.leaderRev, a.demotedCh, err)
+		return false
+	}
+	if errors.Is(err, ErrBlockExists) {
+		// it comes through the watch, seal again after it's applied
+		logger.Warningf(logger.Application, "failed to put block: %v", err)
+		a.chainHead = max(a.chainHead, block.Number)
+		return false
+	}
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to put block: %v", err)
+		return false
+	}
+	sealedBlocks.Add(reason, 1)
+	logger.Infof(logger.Application, "block %d sealed by %s: %d ops", block.Number, reason, len(ops))
+
+	for _, hash := range hashes {
+		a.notify(OpStatus{ID: hash, State: OpInBlock, Block: block.Number})
+	}
+	results := a.applyBlock(block, blockHash, ops)
+	for i, hash := range hashes {
+		a.notify(OpStatus{
+			ID:     hash,
+			State:  OpCommitted,
+			Block:  block.Number,
+			Result: results[i].value,
+			Err:    results[i].err,
+		})
+		delete(a.ackedAt, hash)
+	}
+	a.ackedHashes = slices.Clone(a.ackedHashes[len(ops):])
+	return true
+}
+
+// new leader continues the chain after the last block in etcd
+// blocks the node hasn't applied yet go through the catch up first
+func (a *application) syncChain(ctx context.Context, deliver func(Block)) error {
+	resp, err := a.cli.Get(ctx, HashesKey+"/", clientv3.WithLastKey()...)
+	if err != nil {
+		return fmt.Errorf("failed to get the last block: %w", err)
+	}
+
+	last := int64(-1)
+	if len(resp.Kvs) > 0 {
+		block, err := decodeBlock(resp.Kvs[0].Value)
+		if err != nil {
+			return err
+		}
+		last = block.Number
+
+		// the ones before it are fetched by the catch up
+		if a.nextBlock() <= last {
+			deliver(block)
+		}
+	}
+
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	a.chainHead = last
+	logger.Infof(logger.Application, "continue the chain after block %d", last)
+	a.sealBlockIfCan(a.cli)
+	return nil
+}
+
+// appends block operations to the confirmed ones
+// and applies them to the state machine
+// should be called under opMU
+func (a *application) applyBlock(block Block, blockHash string, ops []Operation) []applyResult {
+	if err := a.logRecord(walRecord{Kind: walBlock, Block: &block, Ops: ops, Raw: a.rawOf(block.TxIDs...)}); err != nil {
+		// the block is in etcd anyway
+		// TODO: fetch blocks that are missing in the wal after restart
+		logger.Errorf(logger.Application, "failed to log block %d: %v", block.Number, err)
+	}
+	results := a.appendBlockOps(block, blockHash, ops)
+
+	if a.snapshotEvery > 0 && (block.Number+1)%a.snapshotEvery == 0 {
+		if err := a.takeSnapshot(); err != nil {
+			logger.Errorf(logger.Application, "failed to take snapshot at block %d: %v", block.Number, err)
+		}
+	}
+	return results
+}
+
+type applyResult struct {
+	value []byte
+	err   error
+}
+
+// should be called under opMU
+func (a *application) appendBlockOps(block Block, blockHash string, ops []Operation) []applyResult {
+	results := make([]applyResult, len(ops))
+	for i, op := range ops {
+		hash := block.TxIDs[i]
+		a.confirmedIdx[hash] = a.compactedOps + len(a.confirmedOps)
+		a.confirmedOps = append(a.confirmedOps, op)
+		if e, ok := a.dedup.lookup(op); ok {
+			// client retry of the request applied in an earlier block
+			logger.Debugf(logger.Application, "op %v of block %d is a retry of %s/%d from block %d", hash, block.Number, op.ClientID, op.Seq, e.block)
+			results[i] = e.result
+		} else {
+			value, err := a.sm.Apply(op)
+			if err != nil {
+				logger.Debugf(logger.Application, "op %v of block %d: %v", hash, block.Number, err)
 			}
-			a.confirmedOps = append(a.confirmedOps, op)
-			delete(a.inFlyOPs, hash)
+			results[i] = applyResult{value: value, err: err}
+			a.dedup.remember(op, block.Number, results[i])
 		}
-		a.ackedHashes = nil
+		delete(a.inFlyOPs, hash)
+		delete(a.receivedOps, hash)
 	}
+	a.dedup.expire(block.Number)
+	a.batchCounter = block.Number + 1
+	a.prevBlockHash = blockHash
+	a.prevBlockRoot = block.Root
+	return results
+}
 
+func (a *application) Demoted() <-chan int64 {
+	return a.demotedCh
 }
 
-func (a *application) AddOp(op Operation) {
-	if !a.isLeader {
-		return
+// p2p.TxStore
+// without the wal the ack doesn't survive a restart
+func (a *application) StoreTx(tx p2p.Transaction) error {
+	op, err := decodeOperation(tx.ID, tx.TxData)
+	if err != nil {
+		// it's the same on every retry
+		return fmt.Errorf("%w: %v", p2p.ErrBadTx, err)
 	}
 
-	hashStr, message := op.HashBin()
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	if _, ok := a.confirmedIdx[tx.ID]; ok {
+		// already in a block, nothing to do
+		return nil
+	}
+	a.keepRaw(tx.ID, op, tx.TxData)
+	if err := a.logRecord(walRecord{Kind: walOp, ID: tx.ID, Op: &op, Raw: a.rawOf(tx.ID)}); err != nil {
+		return err
+	}
+	a.receivedOps[tx.ID] = op
+	return nil
+}
 
+// p2p.TxStore
+func (a *application) GetTxs(ids []string) []p2p.Transaction {
 	a.opMU.Lock()
+	defer a.opMU.Unlock()
+
+	var res []p2p.Transaction
+	for _, id := range ids {
+		op, ok := a.localOp(id)
+		if !ok {
+			continue
+		}
+		message, err := a.encodedOp(id, op)
+		if err != nil {
+			logger.Errorf(logger.Application, "failed to encode op %v: %v", id, err)
+			continue
+		}
+		res = append(res, p2p.Transaction{
+			ID:     id,
+			TxData: message,
+		})
+	}
+	return res
+}
+
+// should be called under opMU
+func (a *application) localOp(id string) (Operation, bool) {
+	if op, ok := a.inFlyOPs[id]; ok {
+		return op, true
+	}
+	if op, ok := a.receivedOps[id]; ok {
+		return op, true
+	}
+	if i, ok := a.confirmedIdx[id]; ok {
+		return a.confirmedOps[i-a.compactedOps], true
+	}
+	return Operation{}, false
+}
+
+// returns the channel with status changes of the operation
+// it's closed when the operation is committed
+// TODO: watchers of in fly operations are never released if the leadership is lost
+func (a *application) AddOp(op Operation) (<-chan OpStatus, error) {
+	if op.ClientID == "" && op.Nonce == 0 {
+		// otherwise the same type and value would be the same operation
+		op.Nonce = rand.Uint64N(math.MaxUint64) + 1
+	}
+	hashStr, message, err := op.HashBin()
+	if err != nil {
+		return nil, err
+	}
+
+	a.opMU.Lock()
+	if !a.isLeader {
+		a.opMU.Unlock()
+		return nil, ErrNotLeader
+	}
+	statusCh := make(chan OpStatus, opStatusBuffer)
+	statusCh <- OpStatus{ID: hashStr, State: OpAccepted}
+	if e, ok := a.dedup.lookup(op); ok {
+		// client retry, the original result goes back
+		statusCh <- OpStatus{
+			ID:     hashStr,
+			State:  OpCommitted,
+			Block:  e.block,
+			Result: e.result.value,
+			Err:    e.result.err,
+		}
+		close(statusCh)
+		a.opMU.Unlock()
+		return statusCh, nil
+	}
+	if _, ok := a.confirmedIdx[hashStr]; ok && op.ClientID != "" {
+		// retry of the request that is out of the dedup window
+		statusCh <- OpStatus{ID: hashStr, State: OpCommitted}
+		close(statusCh)
+		a.opMU.Unlock()
+		return statusCh, nil
+	}
+	if _, ok := a.inFlyOPs[hashStr]; !ok {
+		if err := a.logRecord(walRecord{Kind: walOp, ID: hashStr, Op: &op, Leader: true}); err != nil {
+			a.opMU.Unlock()
+			return nil, err
+		}
+	}
+	// retry of the in fly operation waits for the same block
+	// it's distributed again in case the first attempt didn't reach the followers
+	a.opWatchers[hashStr] = append(a.opWatchers[hashStr], statusCh)
 	a.inFlyOPs[hashStr] = op
 	a.opMU.Unlock()
-	a.p2pDistr.DistributeTx(p2p.Transaction{
+
+	err = a.p2pDistr.DistributeTx(p2p.Transaction{
 		ID:     hashStr,
 		TxData: message,
 	})
+	if err != nil {
+		// the op stays in fly, some followers could've got it
+		// a retry distributes it again
+		a.opMU.Lock()
+		a.opWatchers[hashStr] = slices.DeleteFunc(a.opWatchers[hashStr], func(ch chan OpStatus) bool { return ch == statusCh })
+		if len(a.opWatchers[hashStr]) == 0 {
+			delete(a.opWatchers, hashStr)
+		}
+		a.opMU.Unlock()
+		if errors.Is(err, p2p.ErrQueueFull) {
+			return nil, fmt.Errorf("%w: %w", ErrOverloaded, err)
+		}
+		return nil, err
+	}
+	return statusCh, nil
 }
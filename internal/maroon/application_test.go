@@ -2,81 +2,755 @@ package maroon
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
-	"strings"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/akantsevoi/test-environment/pkg/logger"
 	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
 type servMock struct {
-	distr func(tx p2p.Transaction)
+	distr    func(tx p2p.Transaction)
+	fetch    func(ctx context.Context, ids []string) ([]p2p.Transaction, error)
+	snapshot func(ctx context.Context) (int64, []byte, error)
+	pending  func(ctx context.Context) ([]p2p.Transaction, error)
+	distrErr error
 }
 
-func (s *servMock) DistributeTx(tx p2p.Transaction) {
+func (s *servMock) DistributeTx(tx p2p.Transaction) error {
+	if s.distrErr != nil {
+		return s.distrErr
+	}
 	s.distr(tx)
+	return nil
 }
 
-type etcdMock struct {
-	put func(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+func (s *servMock) FetchTxs(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+	return s.fetch(ctx, ids)
 }
 
-func (e *etcdMock) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
-	return e.put(ctx, key, val, opts...)
+func (s *servMock) FetchPendingTxs(ctx context.Context) ([]p2p.Transaction, error) {
+	if s.pending == nil {
+		return nil, nil
+	}
+	return s.pending(ctx)
 }
 
-func TestCheckProofSentToETCD(t *testing.T) {
-	var etcdValueRequest string
-	etcd := &etcdMock{
-		put: func(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
-			log.Println("etcd", key, val)
-			etcdValueRequest = val
-			return &clientv3.PutResponse{}, nil
-		},
+func (s *servMock) FetchSnapshot(ctx context.Context) (int64, []byte, error) {
+	if s.snapshot == nil {
+		return 0, nil, errors.New("no snapshots")
 	}
+	return s.snapshot(ctx)
+}
+
+func hashBin(t *testing.T, op Operation) (string, []byte) {
+	hash, message, err := op.HashBin()
+	require.NoError(t, err)
+	return hash, message
+}
+
+// etcd where the node with the returned leadership is the leader
+func leaderETCD(t *testing.T) (etcdmock.ETCDMock, Leadership) {
+	etcd := etcdmock.New()
+	resp, err := etcd.Put(context.Background(), LeaderKey, "node-1")
+	require.NoError(t, err)
+	return etcd, Leadership{IsLeader: true, Revision: resp.Header.Revision}
+}
+
+func TestCheckProofSentToETCD(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
 
 	opDistributedCh := make(chan p2p.TransactionDistributed)
 
+	// confirmations are sent by the test in a fixed order
+	// so the merkle root is predictable
+	var distributed []string
 	serv := &servMock{
 		distr: func(tx p2p.Transaction) {
 			log.Println("server imitation", tx)
-			go func() {
-				time.Sleep(10 * time.Millisecond)
-				opDistributedCh <- p2p.TransactionDistributed{
-					ID: tx.ID,
-				}
-			}()
+			distributed = append(distributed, tx.ID)
 		},
 	}
 
-	isLeaderCh := make(chan bool)
+	isLeaderCh := make(chan Leadership)
 	etcdWatchCh := make(clientv3.WatchChan)
 	stopCh := make(chan struct{})
 
 	app := New(etcd, serv)
 	go app.Run(isLeaderCh, opDistributedCh, etcdWatchCh, stopCh)
-	isLeaderCh <- true
+	isLeaderCh <- leadership
 
-	op1, op2, op3 := Operation{OpType: PrintTimestamp, Value: "1"}, Operation{OpType: PrintTimestamp, Value: "2"}, Operation{OpType: PrintTimestamp, Value: "3"}
+	op1, op2, op3 := Operation{OpType: PrintTimestamp, Value: "1", Nonce: 1}, Operation{OpType: PrintTimestamp, Value: "2", Nonce: 2}, Operation{OpType: PrintTimestamp, Value: "3", Nonce: 3}
 
 	app.AddOp(op1)
 	app.AddOp(op2)
 	app.AddOp(op3)
 
+	for _, id := range distributed {
+		opDistributedCh <- p2p.TransactionDistributed{ID: id}
+	}
+
 	time.Sleep(50 * time.Millisecond)
 	stopCh <- struct{}{}
 
 	time.Sleep(50 * time.Millisecond)
-	require.ElementsMatch(t,
-		strings.Split(etcdValueRequest, ","),
-		[]string{
-			op1.Hash(),
-			op2.Hash(),
-			op3.Hash(),
+	resp, err := etcd.Get(context.Background(), blockKey(0))
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), block.Number)
+	require.Empty(t, block.PrevHash)
+	require.Equal(t, 3, block.LeafCount)
+
+	hashes := []string{op1.Hash(), op2.Hash(), op3.Hash()}
+	expected, err := newBlock(0, "", hashes)
+	require.NoError(t, err)
+	require.Equal(t, expected.Root, block.Root)
+
+	for i, op := range []Operation{op1, op2, op3} {
+		proof, err := OpProof(hashes, i)
+		require.NoError(t, err)
+		require.True(t, block.Contains(op, proof))
+	}
+
+	proof, err := OpProof(hashes, 0)
+	require.NoError(t, err)
+	require.False(t, block.Contains(op2, proof))
+}
+
+func TestFollowerFetchesMissingTxs(t *testing.T) {
+	op1, op2, op3 := Operation{OpType: PrintTimestamp, Value: "1"}, Operation{OpType: PrintTimestamp, Value: "2"}, Operation{OpType: PrintTimestamp, Value: "3"}
+	peerOps := map[string]Operation{}
+	for _, op := range []Operation{op1, op2, op3} {
+		peerOps[op.Hash()] = op
+	}
+
+	var fetchCalls atomic.Int32
+	serv := &servMock{
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			// first attempt: peers know nothing yet
+			if fetchCalls.Add(1) == 1 {
+				return nil, errors.New("not found")
+			}
+			var res []p2p.Transaction
+			for _, id := range ids {
+				op := peerOps[id]
+				_, message := hashBin(t, op)
+				res = append(res, p2p.Transaction{ID: id, TxData: message})
+			}
+			return res, nil
+		},
+	}
+
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	app := New(etcdmock.New(), serv)
+	go app.Run(make(chan Leadership), make(chan p2p.TransactionDistributed), etcdWatchCh, stopCh)
+
+	block, err := newBlock(0, "", []string{op1.Hash(), op2.Hash(), op3.Hash()})
+	require.NoError(t, err)
+	record, err := block.Encode()
+	require.NoError(t, err)
+
+	etcdWatchCh <- clientv3.WatchResponse{
+		Events: []*clientv3.Event{
+			{
+				Type: clientv3.EventTypePut,
+				Kv: &mvccpb.KeyValue{
+					Key:   []byte(blockKey(0)),
+					Value: record,
+				},
+			},
+		},
+	}
+
+	require.Eventually(t, func() bool {
+		app.opMU.Lock()
+		defer app.opMU.Unlock()
+		return len(app.confirmedOps) == 3
+	}, time.Second, 10*time.Millisecond)
+	stopCh <- struct{}{}
+
+	require.Equal(t, []Operation{op1, op2, op3}, app.confirmedOps)
+	require.Equal(t, int64(1), app.batchCounter)
+	require.EqualValues(t, 2, fetchCalls.Load())
+
+	// now the follower can serve them to the others
+	require.Len(t, app.GetTxs([]string{op2.Hash(), "unknown"}), 1)
+}
+
+func TestFollowerUsesReceivedTxs(t *testing.T) {
+	op1, op2 := Operation{OpType: PrintTimestamp, Value: "1"}, Operation{OpType: PrintTimestamp, Value: "2"}
+
+	serv := &servMock{
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			t.Errorf("unexpected fetch of %v", ids)
+			return nil, errors.New("unexpected")
+		},
+	}
+	app := New(etcdmock.New(), serv)
+
+	for _, op := range []Operation{op1, op2} {
+		hash, message := hashBin(t, op)
+		require.NoError(t, app.StoreTx(p2p.Transaction{ID: hash, TxData: message}))
+	}
+	_, message := hashBin(t, op1)
+	require.ErrorIs(t, app.StoreTx(p2p.Transaction{ID: op2.Hash(), TxData: message}), p2p.ErrBadTx)
+
+	block, err := newBlock(0, "", []string{op2.Hash(), op1.Hash()})
+	require.NoError(t, err)
+	ops, err := app.collectBlockOps(context.Background(), block)
+	require.NoError(t, err)
+	require.Equal(t, []Operation{op2, op1}, ops)
+
+	blockHash, err := block.Hash()
+	require.NoError(t, err)
+	app.applyBlock(block, blockHash, ops)
+	require.Empty(t, app.receivedOps)
+}
+
+// chain of blocks with 3 operations each
+func testChain(t *testing.T, ops []Operation) []Block {
+	var blocks []Block
+	prevHash := ""
+	for i := 0; i < len(ops); i += 3 {
+		var hashes []string
+		for _, op := range ops[i : i+3] {
+			hashes = append(hashes, op.Hash())
+		}
+		block, err := newBlock(int64(len(blocks)), prevHash, hashes)
+		require.NoError(t, err)
+		prevHash, err = block.Hash()
+		require.NoError(t, err)
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func blockEvent(t *testing.T, block Block) *clientv3.Event {
+	record, err := block.Encode()
+	require.NoError(t, err)
+	return &clientv3.Event{
+		Type: clientv3.EventTypePut,
+		Kv:   &mvccpb.KeyValue{Key: []byte(blockKey(block.Number)), Value: record},
+	}
+}
+
+// the ops are known to the peers
+func peersWith(t *testing.T, ops []Operation) *servMock {
+	return &servMock{
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			var res []p2p.Transaction
+			for _, op := range ops {
+				hash, message := hashBin(t, op)
+				if slices.Contains(ids, hash) {
+					res = append(res, p2p.Transaction{ID: hash, TxData: message})
+				}
+			}
+			return res, nil
+		},
+	}
+}
+
+func TestFollowerFetchesMissingBlocks(t *testing.T) {
+	ops := testOps(0, 9)
+	blocks := testChain(t, ops)
+	etcd := etcdmock.New()
+	for _, block := range blocks[:2] {
+		record, err := block.Encode()
+		require.NoError(t, err)
+		_, err = etcd.Put(context.Background(), blockKey(block.Number), string(record))
+		require.NoError(t, err)
+	}
+
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	app := New(etcd, peersWith(t, ops))
+	go app.Run(make(chan Leadership), make(chan p2p.TransactionDistributed), etcdWatchCh, stopCh)
+	defer close(stopCh)
+
+	// events of the first blocks are lost
+	etcdWatchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{blockEvent(t, blocks[2])}}
+
+	require.Eventually(t, func() bool {
+		app.opMU.Lock()
+		defer app.opMU.Unlock()
+		return app.batchCounter == 3
+	}, time.Second, 10*time.Millisecond)
+	app.opMU.Lock()
+	defer app.opMU.Unlock()
+	require.Equal(t, ops, app.confirmedOps)
+}
+
+func TestForkedChainIsFatal(t *testing.T) {
+	var fatal atomic.Bool
+	fatalf = func(_ logger.Domain, format string, v ...interface{}) {
+		fatal.Store(true)
+	}
+	defer func() { fatalf = logger.Fatalf }()
+
+	ops := testOps(0, 6)
+	blocks := testChain(t, ops)
+	forked, err := newBlock(1, "other", blocks[1].TxIDs)
+	require.NoError(t, err)
+
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	app := New(etcdmock.New(), peersWith(t, ops))
+	go app.Run(make(chan Leadership), make(chan p2p.TransactionDistributed), etcdWatchCh, stopCh)
+	defer close(stopCh)
+
+	etcdWatchCh <- clientv3.WatchResponse{Events: []*clientv3.Event{blockEvent(t, blocks[0]), blockEvent(t, forked)}}
+
+	require.Eventually(t, fatal.Load, time.Second, 10*time.Millisecond)
+	app.opMU.Lock()
+	defer app.opMU.Unlock()
+	require.Equal(t, int64(1), app.batchCounter)
+}
+
+func TestRunIsNotBlockedByCatchUp(t *testing.T) {
+	ops := testOps(0, 3)
+	blocks := testChain(t, ops)
+	var noDeadline atomic.Bool
+	serv := &servMock{
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				noDeadline.Store(true)
+			}
+			// peers don't answer
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	app := New(etcdmock.New(), serv)
+	go app.Run(make(chan Leadership), make(chan p2p.TransactionDistributed), etcdWatchCh, stopCh)
+
+	// more than fits into the catch up queue
+	var events []*clientv3.Event
+	for range maxPendingBlocks + 10 {
+		events = append(events, blockEvent(t, blocks[0]))
+	}
+	etcdWatchCh <- clientv3.WatchResponse{Events: events}
+
+	select {
+	case stopCh <- struct{}{}:
+	case <-time.After(time.Second):
+		t.Fatal("run loop is blocked")
+	}
+	require.False(t, noDeadline.Load())
+}
+
+func TestAddOpStatuses(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	serv := &servMock{distr: func(tx p2p.Transaction) {}}
+
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, serv)
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+
+	isLeaderCh <- Leadership{}
+	_, err := app.AddOp(Operation{OpType: PrintTimestamp, Value: "0"})
+	require.ErrorIs(t, err, ErrNotLeader)
+
+	isLeaderCh <- leadership
+	var chs []<-chan OpStatus
+	var ids []string
+	for i, v := range []string{"1", "2", "3"} {
+		op := Operation{OpType: PrintTimestamp, Value: v, Nonce: uint64(i + 1)}
+		// leadership is applied by the Run loop asynchronously
+		require.Eventually(t, func() bool {
+			ch, err := app.AddOp(op)
+			if err != nil {
+				return false
+			}
+			chs = append(chs, ch)
+			return true
+		}, time.Second, 10*time.Millisecond)
+		ids = append(ids, op.Hash())
+	}
+	for _, id := range ids {
+		opDistributedCh <- p2p.TransactionDistributed{ID: id}
+	}
+
+	for i, ch := range chs {
+		var states []OpState
+		for st := range ch {
+			require.Equal(t, ids[i], st.ID)
+			states = append(states, st.State)
+		}
+		require.Equal(t, []OpState{OpAccepted, OpDistributed, OpInBlock, OpCommitted}, states)
+	}
+}
+
+func TestOpsWithoutClientAreNotDeduplicated(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, &servMock{distr: func(tx p2p.Transaction) {}})
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	op := Operation{OpType: PrintTimestamp, Value: "twice"}
+	for i := range 2 {
+		var ch <-chan OpStatus
+		require.Eventually(t, func() bool {
+			var err error
+			ch, err = app.AddOp(op)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+		accepted := <-ch
+		opDistributedCh <- p2p.TransactionDistributed{ID: accepted.ID}
+
+		var last OpStatus
+		for st := range ch {
+			last = st
+		}
+		require.Equal(t, OpCommitted, last.State)
+		require.Equal(t, int64(i), last.Block)
+	}
+}
+
+func TestOverloadedAddOpCanBeRetried(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	var distributed []string
+	serv := &servMock{
+		distr:    func(tx p2p.Transaction) { distributed = append(distributed, tx.ID) },
+		distrErr: fmt.Errorf("%w: tx is queued for 0 of 2 peers", p2p.ErrQueueFull),
+	}
+	app := New(etcd, serv)
+	app.isLeader, app.leaderRev = true, leadership.Revision
+
+	// only client requests can be retried, the others are new operations every time
+	op := Operation{OpType: PrintTimestamp, Value: "1", ClientID: "c1", Seq: 1}
+	_, err := app.AddOp(op)
+	require.ErrorIs(t, err, ErrOverloaded)
+	require.Empty(t, app.opWatchers)
+	// followers could've got it already
+	require.Contains(t, app.inFlyOPs, op.Hash())
+
+	serv.distrErr = nil
+	_, err = app.AddOp(op)
+	require.NoError(t, err)
+	require.Len(t, app.opWatchers[op.Hash()], 1)
+	require.Equal(t, []string{op.Hash()}, distributed)
+}
+
+func TestDemotionReplacesStaleSignal(t *testing.T) {
+	demotedCh := make(chan int64, 1)
+	isLeader := true
+	demote(&isLeader, 5, demotedCh, ErrFenced)
+	// nobody read it and the node became the leader again
+	isLeader = true
+	demote(&isLeader, 7, demotedCh, ErrFenced)
+
+	require.False(t, isLeader)
+	require.Equal(t, int64(7), <-demotedCh)
+	require.Empty(t, demotedCh)
+}
+
+func TestFencedLeaderIsDemoted(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, &servMock{distr: func(tx p2p.Transaction) {}})
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	ops := testOps(0, 3)
+	for _, op := range ops {
+		require.Eventually(t, func() bool {
+			_, err := app.AddOp(op)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// lease expired and another node became the leader
+	_, err := etcd.Delete(context.Background(), LeaderKey)
+	require.NoError(t, err)
+	_, err = etcd.Put(context.Background(), LeaderKey, "node-2")
+	require.NoError(t, err)
+
+	for _, op := range ops {
+		opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+	}
+
+	select {
+	case rev := <-app.Demoted():
+		require.Equal(t, leadership.Revision, rev)
+	case <-time.After(time.Second):
+		t.Fatal("leader is not demoted")
+	}
+	_, err = app.AddOp(Operation{OpType: PrintTimestamp, Value: "next"})
+	require.ErrorIs(t, err, ErrNotLeader)
+
+	resp, err := etcd.Get(context.Background(), HashesKey+"/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Empty(t, resp.Kvs)
+}
+
+// serves operations to the catch up
+func opsServMock(t *testing.T, ops []Operation) *servMock {
+	byID := make(map[string]Operation)
+	for _, op := range ops {
+		byID[op.Hash()] = op
+	}
+	return &servMock{
+		distr: func(tx p2p.Transaction) {},
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			var res []p2p.Transaction
+			for _, id := range ids {
+				op, ok := byID[id]
+				if !ok {
+					continue
+				}
+				_, message := hashBin(t, op)
+				res = append(res, p2p.Transaction{ID: id, TxData: message})
+			}
+			return res, nil
+		},
+	}
+}
+
+// puts the block of the operations on top of prevHash as if it was done by another leader
+func putTestBlock(t *testing.T, etcd ETCD, number int64, prevHash string, ops []Operation) Block {
+	var hashes []string
+	for _, op := range ops {
+		hashes = append(hashes, op.Hash())
+	}
+	block, err := newBlock(number, prevHash, hashes)
+	require.NoError(t, err)
+	block.Term = 1
+	record, err := block.Encode()
+	require.NoError(t, err)
+	_, err = etcd.Put(context.Background(), blockKey(number), string(record))
+	require.NoError(t, err)
+	return block
+}
+
+func addAndAck(t *testing.T, app *application, opDistributedCh chan<- p2p.TransactionDistributed, ops []Operation) {
+	for _, op := range ops {
+		require.Eventually(t, func() bool {
+			_, err := app.AddOp(op)
+			return err == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+	for _, op := range ops {
+		opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+	}
+}
+
+func TestNewLeaderContinuesChain(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	ops := testOps(0, 9)
+
+	// previous leader managed to put two blocks
+	block0 := putTestBlock(t, etcd, 0, "", ops[:3])
+	hash0, err := block0.Hash()
+	require.NoError(t, err)
+	block1 := putTestBlock(t, etcd, 1, hash0, ops[3:6])
+	hash1, err := block1.Hash()
+	require.NoError(t, err)
+
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, opsServMock(t, ops[:6]))
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	addAndAck(t, app, opDistributedCh, ops[6:])
+
+	var resp *clientv3.GetResponse
+	require.Eventually(t, func() bool {
+		resp, err = etcd.Get(context.Background(), blockKey(2))
+		return err == nil && len(resp.Kvs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), block.Number)
+	require.Equal(t, hash1, block.PrevHash)
+	require.Equal(t, leadership.Revision, block.Term)
+
+	app.opMU.Lock()
+	defer app.opMU.Unlock()
+	require.Equal(t, ops, app.confirmedOps)
+}
+
+func TestLeaderDoesNotOverwriteBlocks(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	ops := testOps(0, 6)
+
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, opsServMock(t, ops[:3]))
+	go app.Run(isLeaderCh, opDistributedCh, etcdWatchCh, stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	// shows up after the new leader looked at the chain
+	block0 := putTestBlock(t, etcd, 0, "", ops[:3])
+	record0, err := block0.Encode()
+	require.NoError(t, err)
+
+	addAndAck(t, app, opDistributedCh, ops[3:])
+
+	resp, err := etcd.Get(context.Background(), HashesKey+"/", clientv3.WithPrefix())
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	require.Equal(t, record0, resp.Kvs[0].Value)
+
+	etcdWatchCh <- clientv3.WatchResponse{
+		Events: []*clientv3.Event{{
+			Type: clientv3.EventTypePut,
+			Kv:   &mvccpb.KeyValue{Key: []byte(blockKey(0)), Value: record0},
+		}},
+	}
+
+	require.Eventually(t, func() bool {
+		resp, err = etcd.Get(context.Background(), blockKey(1))
+		return err == nil && len(resp.Kvs) == 1
+	}, time.Second, 10*time.Millisecond)
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	require.NoError(t, err)
+	hash0, err := block0.Hash()
+	require.NoError(t, err)
+	require.Equal(t, hash0, block.PrevHash)
+	require.Equal(t, []string{ops[3].Hash(), ops[4].Hash(), ops[5].Hash()}, block.TxIDs)
+}
+
+func TestNewLeaderRecoversPendingOps(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	ops := testOps(0, 3)
+
+	var mu sync.Mutex
+	var distributed []string
+	serv := &servMock{
+		distr: func(tx p2p.Transaction) {
+			mu.Lock()
+			defer mu.Unlock()
+			distributed = append(distributed, tx.ID)
+		},
+		// followers acked them for the previous leader
+		pending: func(ctx context.Context) ([]p2p.Transaction, error) {
+			var res []p2p.Transaction
+			for _, op := range ops[1:] {
+				_, message := hashBin(t, op)
+				res = append(res, p2p.Transaction{ID: op.Hash(), TxData: message})
+			}
+			return res, nil
 		},
-	)
+	}
+
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, serv)
+	// the new leader got one of them itself
+	_, message := hashBin(t, ops[0])
+	require.NoError(t, app.StoreTx(p2p.Transaction{ID: ops[0].Hash(), TxData: message}))
+
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	var ids []string
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		ids = slices.Clone(distributed)
+		return len(ids) == 3
+	}, time.Second, 10*time.Millisecond)
+	require.True(t, slices.IsSorted(ids))
+
+	for _, id := range ids {
+		opDistributedCh <- p2p.TransactionDistributed{ID: id}
+	}
+
+	var resp *clientv3.GetResponse
+	require.Eventually(t, func() bool {
+		var err error
+		resp, err = etcd.Get(context.Background(), blockKey(0))
+		return err == nil && len(resp.Kvs) == 1
+	}, time.Second, 10*time.Millisecond)
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, ids, block.TxIDs)
+
+	app.opMU.Lock()
+	defer app.opMU.Unlock()
+	require.Empty(t, app.receivedOps)
+	require.Empty(t, app.inFlyOPs)
+}
+
+func TestRecoveryRetriesPendingFetch(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	op := Operation{OpType: PrintTimestamp, Value: "pending"}
+
+	var mu sync.Mutex
+	var attempts int
+	distributedCh := make(chan string, 1)
+	serv := &servMock{
+		distr: func(tx p2p.Transaction) {
+			distributedCh <- tx.ID
+		},
+		pending: func(ctx context.Context) ([]p2p.Transaction, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts++
+			if attempts == 1 {
+				return nil, errors.New("peers are not reachable")
+			}
+			hash, message := hashBin(t, op)
+			return []p2p.Transaction{{ID: hash, TxData: message}}, nil
+		},
+	}
+
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+	app := New(etcd, serv)
+	go app.Run(isLeaderCh, make(chan p2p.TransactionDistributed), make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
 
+	select {
+	case id := <-distributedCh:
+		require.Equal(t, op.Hash(), id)
+	case <-time.After(time.Second):
+		t.Fatal("pending op is not recovered")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 2, attempts)
 }
@@ -0,0 +1,119 @@
+package maroon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/akantsevoi/test-environment/pkg/merkle"
+)
+
+// Block is a record that leader puts into etcd under blockKey(Number)
+// it doesn't contain the operations themselves, only their hashes and a commitment to them
+// so anyone who has an operation and a proof can check that it's in the block
+type Block struct {
+	Number int64 `json:"number"`
+	// create revision of the leader key of the leader that issued the block
+	// grows with every new leader
+	Term int64 `json:"term"`
+
+	// hash of the previous block record, empty for the first block
+	PrevHash string `json:"prevHash"`
+
+	// hex encoded merkle root built from the operation hashes in the block order
+	Root      string `json:"root"`
+	LeafCount int    `json:"leafCount"`
+
+	// operation hashes in the block order
+	// followers use them to find out which transactions they miss
+	TxIDs []string `json:"txIDs"`
+}
+
+func newBlock(number int64, prevHash string, opHashes []string) (Block, error) {
+	tree, err := opTree(opHashes)
+	if err != nil {
+		return Block{}, err
+	}
+
+	return Block{
+		Number:    number,
+		PrevHash:  prevHash,
+		Root:      hex.EncodeToString(tree.Root()),
+		LeafCount: tree.Len(),
+		TxIDs:     append([]string(nil), opHashes...),
+	}, nil
+}
+
+// numbers are zero padded, so etcd keeps the blocks in order
+func blockKey(number int64) string {
+	return fmt.Sprintf("%s/%020d", HashesKey, number)
+}
+
+// decodes and checks that the tx ids match the merkle root
+func decodeBlock(data []byte) (Block, error) {
+	var b Block
+	if err := json.Unmarshal(data, &b); err != nil {
+		return Block{}, fmt.Errorf("failed to decode block: %w", err)
+	}
+
+	expected, err := newBlock(b.Number, b.PrevHash, b.TxIDs)
+	if err != nil {
+		return Block{}, err
+	}
+	if expected.Root != b.Root || expected.LeafCount != b.LeafCount {
+		return Block{}, fmt.Errorf("block %d: tx ids don't match the root", b.Number)
+	}
+	return b, nil
+}
+
+func (b Block) Encode() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// hash of the whole record, next block references it
+func (b Block) Hash() (string, error) {
+	data, err := b.Encode()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// checks that the operation is included into the block
+// proof is produced by OpProof by any node that has all the block operations
+func (b Block) Contains(op Operation, proof merkle.Proof) bool {
+	if proof.Size != b.LeafCount {
+		return false
+	}
+	root, err := hex.DecodeString(b.Root)
+	if err != nil {
+		return false
+	}
+	leaf, err := hex.DecodeString(op.Hash())
+	if err != nil {
+		return false
+	}
+	return merkle.Verify(root, leaf, proof)
+}
+
+// inclusion proof for the operation with index in the ordered block hashes
+func OpProof(opHashes []string, index int) (merkle.Proof, error) {
+	tree, err := opTree(opHashes)
+	if err != nil {
+		return merkle.Proof{}, err
+	}
+	return tree.Proof(index)
+}
+
+func opTree(opHashes []string) (*merkle.Tree, error) {
+	leaves := make([][]byte, len(opHashes))
+	for i, h := range opHashes {
+		leaf, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("bad operation hash %q: %w", h, err)
+		}
+		leaves[i] = leaf
+	}
+	return merkle.New(leaves), nil
+}
@@ -0,0 +1,232 @@
+package maroon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	// how many blocks from etcd can wait for the catch up
+	// node that is behind for more installs a snapshot
+	maxPendingBlocks = 1024
+
+	minFetchBackoff = 100 * time.Millisecond
+	maxFetchBackoff = 5 * time.Second
+	// a single FetchTxs call, peers that don't answer are retried later
+	fetchTxsTimeout = 5 * time.Second
+)
+
+// the chain in etcd and the applied one diverged, nothing can be applied after that
+// a variable for tests
+var fatalf = logger.Fatalf
+
+var errNotFollowing = errors.New("block doesn't follow the last applied one")
+
+// follower side
+// goes through the committed blocks in order, collects all their operations
+// locally or from the neighbours and only then applies the block
+// blocks before the received one that weren't applied are fetched from etcd
+func (a *application) catchUp(ctx context.Context, blocksCh <-chan Block) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case block := <-blocksCh:
+			if err := a.catchUpTo(ctx, block); err != nil {
+				// can only be cancelled context
+				return
+			}
+		}
+	}
+}
+
+// retries until the block is applied
+func (a *application) catchUpTo(ctx context.Context, block Block) error {
+	backoff := minFetchBackoff
+	for {
+		next := a.nextBlock()
+		if block.Number < next {
+			logger.Debugf(logger.Application, "block %d is already applied", block.Number)
+			return nil
+		}
+
+		var err error
+		if block.Number == next {
+			err = a.applyNext(ctx, block)
+			if err == nil {
+				return nil
+			}
+		} else {
+			err = a.fillGap(ctx, next, block.Number)
+			if err == nil {
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		logger.Warningf(logger.Application, "failed to catch up to block %d, retry in %v: %v", block.Number, backoff, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxFetchBackoff)
+	}
+}
+
+func (a *application) nextBlock() int64 {
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	return a.batchCounter
+}
+
+// applies some of the blocks [from, to), returns nil if there is progress
+// node that is too far behind installs a snapshot first, otherwise blocks come from etcd
+// and the snapshot is the last resort
+func (a *application) fillGap(ctx context.Context, from, to int64) error {
+	farBehind := to-from >= maxPendingBlocks
+	if farBehind && a.trySnapshot(ctx, from) {
+		return nil
+	}
+	applied, err := a.applyETCDBlocks(ctx, from, to)
+	if applied > 0 {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("blocks [%d, %d) are not in etcd", from, to)
+	}
+	if !farBehind && a.trySnapshot(ctx, from) {
+		return nil
+	}
+	return err
+}
+
+// returns true if the snapshot is after the block
+func (a *application) trySnapshot(ctx context.Context, from int64) bool {
+	if err := a.installSnapshot(ctx); err != nil {
+		logger.Warningf(logger.Application, "block %d is missing, failed to install snapshot: %v", from, err)
+		return false
+	}
+	return a.nextBlock() > from
+}
+
+// applies blocks [from, to) from etcd in order, at most maxPendingBlocks at once
+func (a *application) applyETCDBlocks(ctx context.Context, from, to int64) (int, error) {
+	resp, err := a.cli.Get(ctx, blockKey(from), clientv3.WithRange(blockKey(to)), clientv3.WithLimit(maxPendingBlocks))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get blocks [%d, %d): %w", from, to, err)
+	}
+	for i, kv := range resp.Kvs {
+		block, err := decodeBlock(kv.Value)
+		if err != nil {
+			return i, fmt.Errorf("block %s: %w", kv.Key, err)
+		}
+		if err := a.applyNext(ctx, block); err != nil {
+			return i, err
+		}
+	}
+	return len(resp.Kvs), nil
+}
+
+// applies the block that goes right after the last applied one
+func (a *application) applyNext(ctx context.Context, block Block) error {
+	// ops of a block that doesn't follow may never be found, the peers could've compacted them
+	if next := a.nextBlock(); block.Number > next {
+		return fmt.Errorf("%w: block %d, last applied %d", errNotFollowing, block.Number, next-1)
+	}
+	ops, err := a.collectBlockOps(ctx, block)
+	if err != nil {
+		return err
+	}
+
+	blockHash, err := block.Hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash block %d: %w", block.Number, err)
+	}
+
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	if block.Number < a.batchCounter {
+		logger.Infof(logger.Application, "block %d is already applied", block.Number)
+		return nil
+	}
+	if block.Number > a.batchCounter {
+		return fmt.Errorf("%w: block %d, last applied %d", errNotFollowing, block.Number, a.batchCounter-1)
+	}
+	if a.prevBlockHash != "" && block.PrevHash != a.prevBlockHash {
+		fatalf(logger.Application, "block %d doesn't continue the chain: prev hash %v, last applied %v", block.Number, block.PrevHash, a.prevBlockHash)
+		return fmt.Errorf("%w: block %d has prev hash %v", errNotFollowing, block.Number, block.PrevHash)
+	}
+	a.applyBlock(block, blockHash, ops)
+	// leader that waited for the chain to catch up
+	a.sealBlockIfCan(a.cli)
+
+	logger.Infof(logger.Application, "block %d applied: %d ops", block.Number, len(ops))
+	return nil
+}
+
+// retries across peers until every operation of the block is here
+// returns operations in the block order
+func (a *application) collectBlockOps(ctx context.Context, block Block) ([]Operation, error) {
+	fetched := make(map[string]Operation)
+	backoff := minFetchBackoff
+
+	for {
+		var missing []string
+		a.opMU.Lock()
+		for _, id := range block.TxIDs {
+			if _, ok := fetched[id]; ok {
+				continue
+			}
+			if op, ok := a.localOp(id); ok {
+				fetched[id] = op
+				continue
+			}
+			missing = append(missing, id)
+		}
+		a.opMU.Unlock()
+
+		if len(missing) == 0 {
+			break
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, fetchTxsTimeout)
+		txs, err := a.p2pDistr.FetchTxs(fetchCtx, missing)
+		cancel()
+		a.opMU.Lock()
+		for _, tx := range txs {
+			op, err := decodeOperation(tx.ID, tx.TxData)
+			if err != nil {
+				logger.Errorf(logger.Application, "got broken tx from peer: %v", err)
+				continue
+			}
+			a.keepRaw(tx.ID, op, tx.TxData)
+			fetched[tx.ID] = op
+		}
+		a.opMU.Unlock()
+		if err == nil && len(txs) == len(missing) {
+			continue
+		}
+
+		logger.Warningf(logger.Application, "block %d: %d txs are still missing, retry in %v: %v", block.Number, len(missing)-len(txs), backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxFetchBackoff)
+	}
+
+	ops := make([]Operation, len(block.TxIDs))
+	for i, id := range block.TxIDs {
+		ops[i] = fetched[id]
+	}
+	return ops, nil
+}
@@ -3,4 +3,10 @@ package maroon
 const (
 	LeaderKey = "/maroon/leader"
 	HashesKey = "/maroon/hashes"
+	// committed offset vector
+	VectorKey = "/maroon/tn"
+	// key ranges of the gateways, see pkg/keyrange
+	RangesKey = "/maroon/ranges"
+	// registered nodes, see pkg/membership
+	MembersKey = "/maroon/members"
 )
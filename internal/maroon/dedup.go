@@ -0,0 +1,118 @@
+package maroon
+
+import "errors"
+
+const defaultDedupWindow = 1000
+
+// operations of the same client request
+// ops without ClientID are never deduplicated
+type requestKey struct {
+	ClientID string
+	Seq      uint64
+}
+
+func requestOf(op Operation) (requestKey, bool) {
+	if op.ClientID == "" {
+		return requestKey{}, false
+	}
+	return requestKey{ClientID: op.ClientID, Seq: op.Seq}, true
+}
+
+// applied request, part of the snapshot
+type dedupRecord struct {
+	ClientID string `json:"clientID"`
+	Seq      uint64 `json:"seq"`
+	// block where the request was applied first
+	Block  int64  `json:"block"`
+	Result []byte `json:"result,omitempty"`
+	Err    string `json:"err,omitempty"`
+}
+
+type dedupEntry struct {
+	block  int64
+	result applyResult
+}
+
+// remembers results of the requests applied during the last window blocks
+// it's changed only when blocks are applied, so every node skips the same retries
+type dedupTable struct {
+	window int64
+	byKey  map[requestKey]dedupEntry
+	// in the apply order, so the oldest ones are dropped first
+	order []requestKey
+}
+
+func newDedupTable(window int64) *dedupTable {
+	return &dedupTable{
+		window: window,
+		byKey:  make(map[requestKey]dedupEntry),
+	}
+}
+
+// result of the request if it was already applied
+func (d *dedupTable) lookup(op Operation) (dedupEntry, bool) {
+	key, ok := requestOf(op)
+	if !ok {
+		return dedupEntry{}, false
+	}
+	e, ok := d.byKey[key]
+	return e, ok
+}
+
+func (d *dedupTable) remember(op Operation, block int64, result applyResult) {
+	key, ok := requestOf(op)
+	if !ok || d.window <= 0 {
+		return
+	}
+	if _, ok := d.byKey[key]; ok {
+		return
+	}
+	d.byKey[key] = dedupEntry{block: block, result: result}
+	d.order = append(d.order, key)
+}
+
+// forgets requests applied before the window that ends with the block
+func (d *dedupTable) expire(block int64) {
+	n := 0
+	for _, key := range d.order {
+		if d.byKey[key].block > block-d.window {
+			break
+		}
+		delete(d.byKey, key)
+		n++
+	}
+	d.order = d.order[n:]
+}
+
+func (d *dedupTable) records() []dedupRecord {
+	res := make([]dedupRecord, 0, len(d.order))
+	for _, key := range d.order {
+		e := d.byKey[key]
+		rec := dedupRecord{
+			ClientID: key.ClientID,
+			Seq:      key.Seq,
+			Block:    e.block,
+			Result:   e.result.value,
+		}
+		if e.result.err != nil {
+			rec.Err = e.result.err.Error()
+		}
+		res = append(res, rec)
+	}
+	return res
+}
+
+// errors come back only as text, errors.Is doesn't work for them after a restore
+func (d *dedupTable) restore(records []dedupRecord) {
+	d.byKey = make(map[requestKey]dedupEntry, len(records))
+	d.order = make([]requestKey, 0, len(records))
+	for _, rec := range records {
+		key := requestKey{ClientID: rec.ClientID, Seq: rec.Seq}
+		result := applyResult{value: rec.Result}
+		if rec.Err != "" {
+			result.err = errors.New(rec.Err)
+		}
+		d.byKey[key] = dedupEntry{block: rec.Block, result: result}
+		d.order = append(d.order, key)
+	}
+}
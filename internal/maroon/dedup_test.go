@@ -0,0 +1,129 @@
+package maroon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func request(op Operation, clientID string, seq uint64) Operation {
+	op.ClientID, op.Seq = clientID, seq
+	return op
+}
+
+// applies the operations as the next block
+func applyOps(t *testing.T, a *application, ops ...Operation) []applyResult {
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	var hashes []string
+	for _, op := range ops {
+		hashes = append(hashes, op.Hash())
+	}
+	block, err := newBlock(a.batchCounter, a.prevBlockHash, hashes)
+	require.NoError(t, err)
+	blockHash, err := block.Hash()
+	require.NoError(t, err)
+	return a.applyBlock(block, blockHash, ops)
+}
+
+func TestRetryIsAppliedOnce(t *testing.T) {
+	a := New(etcdmock.New(), &servMock{})
+
+	cas := request(NewKVCAS("a", nil, "1"), "c1", 1)
+	res := applyOps(t, a, cas)
+	require.NoError(t, res[0].err)
+
+	// retry got into a later block, it would fail if applied again
+	res = applyOps(t, a, cas)
+	require.NoError(t, res[0].err)
+
+	// the same command as a new request
+	res = applyOps(t, a, request(NewKVCAS("a", nil, "1"), "c1", 2))
+	require.ErrorIs(t, res[0].err, ErrCASMismatch)
+
+	// retries are recognized after a restart from the snapshot
+	a.opMU.Lock()
+	require.NoError(t, a.takeSnapshot())
+	snap, err := decodeSnapshot(a.snapshot)
+	a.opMU.Unlock()
+	require.NoError(t, err)
+
+	restored := New(etcdmock.New(), &servMock{})
+	restored.opMU.Lock()
+	require.NoError(t, restored.restoreSnapshot(snap, nil))
+	restored.opMU.Unlock()
+	res = applyOps(t, restored, cas)
+	require.NoError(t, res[0].err)
+}
+
+func TestDedupWindowExpires(t *testing.T) {
+	a := New(etcdmock.New(), &servMock{}, WithDedupWindow(2))
+
+	cas := request(NewKVCAS("a", nil, "1"), "c1", 1)
+	applyOps(t, a, cas)
+	applyOps(t, a, request(NewKVPut("b", "1"), "c1", 2))
+	res := applyOps(t, a, cas)
+	require.NoError(t, res[0].err)
+
+	applyOps(t, a, request(NewKVPut("b", "2"), "c1", 3))
+	// too late, it's a new request now
+	res = applyOps(t, a, cas)
+	require.ErrorIs(t, res[0].err, ErrCASMismatch)
+}
+
+func TestAddOpRetryGetsOriginalResult(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, &servMock{distr: func(tx p2p.Transaction) {}})
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	// identical commands of different requests are different operations
+	ops := []Operation{
+		request(NewKVPut("a", "1"), "c1", 1),
+		request(NewKVPut("a", "1"), "c1", 2),
+		request(NewKVGet("a"), "c2", 1),
+	}
+	var chs []<-chan OpStatus
+	for _, op := range ops {
+		require.Eventually(t, func() bool {
+			ch, err := app.AddOp(op)
+			if err != nil {
+				return false
+			}
+			chs = append(chs, ch)
+			return true
+		}, time.Second, 10*time.Millisecond)
+	}
+	for _, op := range ops {
+		opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+	}
+	var first OpStatus
+	for _, ch := range chs {
+		for st := range ch {
+			first = st
+		}
+		require.Equal(t, OpCommitted, first.State)
+	}
+
+	// client didn't get the answer and retries the get
+	ch, err := app.AddOp(ops[2])
+	require.NoError(t, err)
+	var states []OpState
+	var retried OpStatus
+	for st := range ch {
+		states = append(states, st.State)
+		retried = st
+	}
+	require.Equal(t, []OpState{OpAccepted, OpCommitted}, states)
+	require.Equal(t, first.Block, retried.Block)
+	require.Equal(t, "1", string(retried.Result))
+}
@@ -8,25 +8,67 @@ import (
 )
 
 type ETCD interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
 	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	// leader writes are transactions guarded by the leadership
+	Txn(ctx context.Context) clientv3.Txn
 }
 
 type DistTransport interface {
-	DistributeTx(m p2p.Transaction)
+	DistributeTx(m p2p.Transaction) error
+	FetchTxs(ctx context.Context, ids []string) ([]p2p.Transaction, error)
+	FetchPendingTxs(ctx context.Context) ([]p2p.Transaction, error)
+	FetchSnapshot(ctx context.Context) (int64, []byte, error)
+}
+
+// see pkg/wal
+type WAL interface {
+	Append(data []byte) (uint64, error)
+	Replay(fn func(index uint64, data []byte) error) error
+	TruncateFront(index uint64) error
+}
+
+type VectorTransport interface {
+	GossipOffsetTxs(txs []p2p.OffsetTx)
+	BroadcastVector(vector map[uint64]uint64)
 }
 
 type Application interface {
-	Run(isLeaderCh <-chan bool, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{})
-	AddOp(op Operation)
+	Run(isLeaderCh <-chan Leadership, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{})
+	AddOp(op Operation) (<-chan OpStatus, error)
+	// fires with the leader revision when a leader write is fenced off
+	// the node stops acting as a leader and should resign if it's still that term
+	Demoted() <-chan int64
+}
+
+type OffsetApplication interface {
+	Run(isLeaderCh <-chan Leadership, vectorWatchCh clientv3.WatchChan, stopCh <-chan struct{})
+	AddTx(key OffsetKey, payload []byte)
+	AddNoOp(key OffsetKey)
+	Demoted() <-chan int64
 }
 
 type OperationType int64
 
 const (
 	PrintTimestamp OperationType = iota
+	KVPut
+	KVGet
+	KVDelete
+	KVCAS
 )
 
+// handled by KV
+var KVTypes = []OperationType{KVPut, KVGet, KVDelete, KVCAS}
+
 type Operation struct {
 	OpType OperationType
 	Value  string
+
+	// request of the client, e.g. gateway range index and offset
+	// retries with the same ClientID and Seq are applied once, see WithDedupWindow
+	ClientID string `json:",omitempty"`
+	Seq      uint64 `json:",omitempty"`
+	// operations without ClientID are never deduplicated, AddOp sets a random one if it's 0
+	Nonce uint64 `json:",omitempty"`
 }
@@ -0,0 +1,117 @@
+package maroon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found")
+	ErrCASMismatch = errors.New("value doesn't match the expected one")
+)
+
+// operation value of the key-value state machine
+type KVCommand struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+	// CAS only, nil - the key shouldn't exist
+	Expected *string `json:"expected,omitempty"`
+}
+
+func kvOp(t OperationType, cmd KVCommand) Operation {
+	value, err := json.Marshal(cmd)
+	if err != nil {
+		// can't happen for a struct of strings
+		panic(err)
+	}
+	return Operation{OpType: t, Value: string(value)}
+}
+
+func NewKVPut(key, value string) Operation {
+	return kvOp(KVPut, KVCommand{Key: key, Value: value})
+}
+
+// read through the log, result is the value
+func NewKVGet(key string) Operation {
+	return kvOp(KVGet, KVCommand{Key: key})
+}
+
+func NewKVDelete(key string) Operation {
+	return kvOp(KVDelete, KVCommand{Key: key})
+}
+
+func NewKVCAS(key string, expected *string, value string) Operation {
+	return kvOp(KVCAS, KVCommand{Key: key, Value: value, Expected: expected})
+}
+
+// key-value state machine
+// operations are applied under the application lock
+// mu is only for local reads with Get
+type KV struct {
+	mu   sync.RWMutex
+	data map[string]string
+}
+
+func NewKV() *KV {
+	return &KV{data: make(map[string]string)}
+}
+
+func (kv *KV) Apply(op Operation) ([]byte, error) {
+	var cmd KVCommand
+	if err := json.Unmarshal([]byte(op.Value), &cmd); err != nil {
+		return nil, fmt.Errorf("bad kv command: %w", err)
+	}
+
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+
+	switch op.OpType {
+	case KVPut:
+		kv.data[cmd.Key] = cmd.Value
+		return nil, nil
+	case KVGet:
+		v, ok := kv.data[cmd.Key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, cmd.Key)
+		}
+		return []byte(v), nil
+	case KVDelete:
+		delete(kv.data, cmd.Key)
+		return nil, nil
+	case KVCAS:
+		v, ok := kv.data[cmd.Key]
+		if cmd.Expected == nil && ok || cmd.Expected != nil && (!ok || v != *cmd.Expected) {
+			return []byte(v), fmt.Errorf("%w: %q", ErrCASMismatch, cmd.Key)
+		}
+		kv.data[cmd.Key] = cmd.Value
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%w: %d", ErrUnknownOpType, op.OpType)
+}
+
+// local read, can be behind the leader
+func (kv *KV) Get(key string) (string, bool) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	v, ok := kv.data[key]
+	return v, ok
+}
+
+func (kv *KV) Snapshot() ([]byte, error) {
+	kv.mu.RLock()
+	defer kv.mu.RUnlock()
+	return json.Marshal(kv.data)
+}
+
+func (kv *KV) Restore(data []byte) error {
+	restored := make(map[string]string)
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("failed to restore kv: %w", err)
+	}
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.data = restored
+	return nil
+}
@@ -0,0 +1,116 @@
+package maroon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestKV(t *testing.T) {
+	kv := NewKV()
+	apply := func(op Operation) ([]byte, error) {
+		return kv.Apply(op)
+	}
+
+	_, err := apply(NewKVGet("a"))
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = apply(NewKVCAS("a", nil, "1"))
+	require.NoError(t, err)
+	_, err = apply(NewKVCAS("a", nil, "2"))
+	require.ErrorIs(t, err, ErrCASMismatch)
+
+	old := "1"
+	_, err = apply(NewKVCAS("a", &old, "2"))
+	require.NoError(t, err)
+	value, err := apply(NewKVGet("a"))
+	require.NoError(t, err)
+	require.Equal(t, "2", string(value))
+
+	_, err = apply(NewKVPut("b", "3"))
+	require.NoError(t, err)
+	_, err = apply(NewKVDelete("a"))
+	require.NoError(t, err)
+	_, ok := kv.Get("a")
+	require.False(t, ok)
+
+	snap, err := kv.Snapshot()
+	require.NoError(t, err)
+	restored := NewKV()
+	require.NoError(t, restored.Restore(snap))
+	v, ok := restored.Get("b")
+	require.True(t, ok)
+	require.Equal(t, "3", v)
+}
+
+func TestRegistry(t *testing.T) {
+	r := DefaultRegistry()
+	require.Error(t, r.Register("other", NewKV(), KVPut))
+
+	_, err := r.Apply(Operation{OpType: 100})
+	require.ErrorIs(t, err, ErrUnknownOpType)
+
+	_, err = r.Apply(NewKVPut("a", "1"))
+	require.NoError(t, err)
+	_, err = r.Apply(Operation{OpType: PrintTimestamp, Value: "10"})
+	require.NoError(t, err)
+
+	snap, err := r.Snapshot()
+	require.NoError(t, err)
+	restored := DefaultRegistry()
+	require.NoError(t, restored.Restore(snap))
+	require.Equal(t, r, restored)
+}
+
+func TestCommittedStatusHasResult(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	kv := NewKV()
+	registry := NewRegistry()
+	registry.MustRegister("kv", kv, KVTypes...)
+	app := New(etcd, &servMock{distr: func(tx p2p.Transaction) {}}, WithStateMachine(registry))
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	ops := []Operation{NewKVPut("a", "1"), NewKVCAS("a", nil, "2"), NewKVGet("a")}
+	for i := range ops {
+		ops[i].Nonce = uint64(i) + 1
+	}
+	var chs []<-chan OpStatus
+	for _, op := range ops {
+		require.Eventually(t, func() bool {
+			ch, err := app.AddOp(op)
+			if err != nil {
+				return false
+			}
+			chs = append(chs, ch)
+			return true
+		}, time.Second, 10*time.Millisecond)
+	}
+	for _, op := range ops {
+		opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+	}
+
+	var committed []OpStatus
+	for _, ch := range chs {
+		var last OpStatus
+		for st := range ch {
+			last = st
+		}
+		committed = append(committed, last)
+	}
+	require.NoError(t, committed[0].Err)
+	require.ErrorIs(t, committed[1].Err, ErrCASMismatch)
+	require.NoError(t, committed[2].Err)
+	require.Equal(t, "1", string(committed[2].Result))
+
+	v, _ := kv.Get("a")
+	require.Equal(t, "1", v)
+}
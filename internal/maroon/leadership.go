@@ -0,0 +1,85 @@
+package maroon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// leader write is rejected by etcd, somebody else is the leader already
+var ErrFenced = errors.New("leader write is fenced off")
+
+// block with the same number is in etcd already, the leader is behind the chain
+var ErrBlockExists = errors.New("block already exists")
+
+type Leadership struct {
+	IsLeader bool
+	// create revision of LeaderKey, set when IsLeader
+	// the key is attached to the leader's lease, so it's a new revision
+	// when the lease expires and somebody else becomes the leader
+	Revision int64
+}
+
+// every leader side write goes through it
+// succeeds only if the leader key is still the one the node created
+func fencedPut(ctx context.Context, cli ETCD, leaderRev int64, key, val string) (*clientv3.TxnResponse, error) {
+	resp, err := cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(LeaderKey), "=", leaderRev)).
+		Then(clientv3.OpPut(key, val)).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Succeeded {
+		return nil, fmt.Errorf("%w: %s is not at revision %d anymore", ErrFenced, LeaderKey, leaderRev)
+	}
+	return resp, nil
+}
+
+// fenced write that doesn't overwrite the key
+// returns ErrBlockExists if the key is there and ErrFenced if the leadership is lost
+func fencedCreate(ctx context.Context, cli ETCD, leaderRev int64, key, val string) error {
+	resp, err := cli.Txn(ctx).
+		If(
+			clientv3.Compare(clientv3.CreateRevision(LeaderKey), "=", leaderRev),
+			clientv3.Compare(clientv3.Version(key), "=", 0),
+		).
+		Then(clientv3.OpPut(key, val)).
+		Else(clientv3.OpGet(LeaderKey)).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if resp.Succeeded {
+		return nil
+	}
+	leader := resp.Responses[0].GetResponseRange()
+	if len(leader.Kvs) == 0 || leader.Kvs[0].CreateRevision != leaderRev {
+		return fmt.Errorf("%w: %s is not at revision %d anymore", ErrFenced, LeaderKey, leaderRev)
+	}
+	return fmt.Errorf("%w: %s", ErrBlockExists, key)
+}
+
+// stops leader writes and tells the election loop to give the leadership up
+// should be called under the lock that guards isLeader
+// the signal carries the revision of the term that was fenced off,
+// so a signal left from an earlier term doesn't resign a later one
+func demote(isLeader *bool, leaderRev int64, demotedCh chan int64, err error) {
+	logger.Errorf(logger.Election, "demoted at revision %d: %v", leaderRev, err)
+	*isLeader = false
+	for {
+		select {
+		case demotedCh <- leaderRev:
+			return
+		default:
+			// nobody read the previous signal, only the latest term matters
+			select {
+			case <-demotedCh:
+			default:
+			}
+		}
+	}
+}
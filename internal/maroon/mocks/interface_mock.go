@@ -43,6 +43,26 @@ func (m *MockETCD) EXPECT() *MockETCDMockRecorder {
 	return m.recorder
 }
 
+// Get mocks base method.
+func (m *MockETCD) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, key}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Get", varargs...)
+	ret0, _ := ret[0].(*clientv3.GetResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockETCDMockRecorder) Get(ctx, key any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, key}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockETCD)(nil).Get), varargs...)
+}
+
 // Put mocks base method.
 func (m *MockETCD) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
 	m.ctrl.T.Helper()
@@ -63,6 +83,20 @@ func (mr *MockETCDMockRecorder) Put(ctx, key, val any, opts ...any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockETCD)(nil).Put), varargs...)
 }
 
+// Txn mocks base method.
+func (m *MockETCD) Txn(ctx context.Context) clientv3.Txn {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Txn", ctx)
+	ret0, _ := ret[0].(clientv3.Txn)
+	return ret0
+}
+
+// Txn indicates an expected call of Txn.
+func (mr *MockETCDMockRecorder) Txn(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Txn", reflect.TypeOf((*MockETCD)(nil).Txn), ctx)
+}
+
 // MockDistTransport is a mock of DistTransport interface.
 type MockDistTransport struct {
 	ctrl     *gomock.Controller
@@ -88,9 +122,11 @@ func (m *MockDistTransport) EXPECT() *MockDistTransportMockRecorder {
 }
 
 // DistributeTx mocks base method.
-func (m_2 *MockDistTransport) DistributeTx(m p2p.Transaction) {
+func (m_2 *MockDistTransport) DistributeTx(m p2p.Transaction) error {
 	m_2.ctrl.T.Helper()
-	m_2.ctrl.Call(m_2, "DistributeTx", m)
+	ret := m_2.ctrl.Call(m_2, "DistributeTx", m)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // DistributeTx indicates an expected call of DistributeTx.
@@ -99,6 +135,167 @@ func (mr *MockDistTransportMockRecorder) DistributeTx(m any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTx", reflect.TypeOf((*MockDistTransport)(nil).DistributeTx), m)
 }
 
+// FetchPendingTxs mocks base method.
+func (m *MockDistTransport) FetchPendingTxs(ctx context.Context) ([]p2p.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchPendingTxs", ctx)
+	ret0, _ := ret[0].([]p2p.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchPendingTxs indicates an expected call of FetchPendingTxs.
+func (mr *MockDistTransportMockRecorder) FetchPendingTxs(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchPendingTxs", reflect.TypeOf((*MockDistTransport)(nil).FetchPendingTxs), ctx)
+}
+
+// FetchSnapshot mocks base method.
+func (m *MockDistTransport) FetchSnapshot(ctx context.Context) (int64, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchSnapshot", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchSnapshot indicates an expected call of FetchSnapshot.
+func (mr *MockDistTransportMockRecorder) FetchSnapshot(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSnapshot", reflect.TypeOf((*MockDistTransport)(nil).FetchSnapshot), ctx)
+}
+
+// FetchTxs mocks base method.
+func (m *MockDistTransport) FetchTxs(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchTxs", ctx, ids)
+	ret0, _ := ret[0].([]p2p.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchTxs indicates an expected call of FetchTxs.
+func (mr *MockDistTransportMockRecorder) FetchTxs(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchTxs", reflect.TypeOf((*MockDistTransport)(nil).FetchTxs), ctx, ids)
+}
+
+// MockWAL is a mock of WAL interface.
+type MockWAL struct {
+	ctrl     *gomock.Controller
+	recorder *MockWALMockRecorder
+	isgomock struct{}
+}
+
+// MockWALMockRecorder is the mock recorder for MockWAL.
+type MockWALMockRecorder struct {
+	mock *MockWAL
+}
+
+// NewMockWAL creates a new mock instance.
+func NewMockWAL(ctrl *gomock.Controller) *MockWAL {
+	mock := &MockWAL{ctrl: ctrl}
+	mock.recorder = &MockWALMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWAL) EXPECT() *MockWALMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockWAL) Append(data []byte) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", data)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockWALMockRecorder) Append(data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockWAL)(nil).Append), data)
+}
+
+// Replay mocks base method.
+func (m *MockWAL) Replay(fn func(uint64, []byte) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replay", fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replay indicates an expected call of Replay.
+func (mr *MockWALMockRecorder) Replay(fn any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replay", reflect.TypeOf((*MockWAL)(nil).Replay), fn)
+}
+
+// TruncateFront mocks base method.
+func (m *MockWAL) TruncateFront(index uint64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TruncateFront", index)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TruncateFront indicates an expected call of TruncateFront.
+func (mr *MockWALMockRecorder) TruncateFront(index any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TruncateFront", reflect.TypeOf((*MockWAL)(nil).TruncateFront), index)
+}
+
+// MockVectorTransport is a mock of VectorTransport interface.
+type MockVectorTransport struct {
+	ctrl     *gomock.Controller
+	recorder *MockVectorTransportMockRecorder
+	isgomock struct{}
+}
+
+// MockVectorTransportMockRecorder is the mock recorder for MockVectorTransport.
+type MockVectorTransportMockRecorder struct {
+	mock *MockVectorTransport
+}
+
+// NewMockVectorTransport creates a new mock instance.
+func NewMockVectorTransport(ctrl *gomock.Controller) *MockVectorTransport {
+	mock := &MockVectorTransport{ctrl: ctrl}
+	mock.recorder = &MockVectorTransportMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVectorTransport) EXPECT() *MockVectorTransportMockRecorder {
+	return m.recorder
+}
+
+// BroadcastVector mocks base method.
+func (m *MockVectorTransport) BroadcastVector(vector map[uint64]uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BroadcastVector", vector)
+}
+
+// BroadcastVector indicates an expected call of BroadcastVector.
+func (mr *MockVectorTransportMockRecorder) BroadcastVector(vector any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BroadcastVector", reflect.TypeOf((*MockVectorTransport)(nil).BroadcastVector), vector)
+}
+
+// GossipOffsetTxs mocks base method.
+func (m *MockVectorTransport) GossipOffsetTxs(txs []p2p.OffsetTx) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GossipOffsetTxs", txs)
+}
+
+// GossipOffsetTxs indicates an expected call of GossipOffsetTxs.
+func (mr *MockVectorTransportMockRecorder) GossipOffsetTxs(txs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GossipOffsetTxs", reflect.TypeOf((*MockVectorTransport)(nil).GossipOffsetTxs), txs)
+}
+
 // MockApplication is a mock of Application interface.
 type MockApplication struct {
 	ctrl     *gomock.Controller
@@ -124,9 +321,12 @@ func (m *MockApplication) EXPECT() *MockApplicationMockRecorder {
 }
 
 // AddOp mocks base method.
-func (m *MockApplication) AddOp(op maroon.Operation) {
+func (m *MockApplication) AddOp(op maroon.Operation) (<-chan maroon.OpStatus, error) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "AddOp", op)
+	ret := m.ctrl.Call(m, "AddOp", op)
+	ret0, _ := ret[0].(<-chan maroon.OpStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // AddOp indicates an expected call of AddOp.
@@ -135,8 +335,22 @@ func (mr *MockApplicationMockRecorder) AddOp(op any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOp", reflect.TypeOf((*MockApplication)(nil).AddOp), op)
 }
 
+// Demoted mocks base method.
+func (m *MockApplication) Demoted() <-chan int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Demoted")
+	ret0, _ := ret[0].(<-chan int64)
+	return ret0
+}
+
+// Demoted indicates an expected call of Demoted.
+func (mr *MockApplicationMockRecorder) Demoted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Demoted", reflect.TypeOf((*MockApplication)(nil).Demoted))
+}
+
 // Run mocks base method.
-func (m *MockApplication) Run(isLeaderCh <-chan bool, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
+func (m *MockApplication) Run(isLeaderCh <-chan maroon.Leadership, distributedTxCh <-chan p2p.TransactionDistributed, etcdWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "Run", isLeaderCh, distributedTxCh, etcdWatchCh, stopCh)
 }
@@ -146,3 +360,77 @@ func (mr *MockApplicationMockRecorder) Run(isLeaderCh, distributedTxCh, etcdWatc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockApplication)(nil).Run), isLeaderCh, distributedTxCh, etcdWatchCh, stopCh)
 }
+
+// MockOffsetApplication is a mock of OffsetApplication interface.
+type MockOffsetApplication struct {
+	ctrl     *gomock.Controller
+	recorder *MockOffsetApplicationMockRecorder
+	isgomock struct{}
+}
+
+// MockOffsetApplicationMockRecorder is the mock recorder for MockOffsetApplication.
+type MockOffsetApplicationMockRecorder struct {
+	mock *MockOffsetApplication
+}
+
+// NewMockOffsetApplication creates a new mock instance.
+func NewMockOffsetApplication(ctrl *gomock.Controller) *MockOffsetApplication {
+	mock := &MockOffsetApplication{ctrl: ctrl}
+	mock.recorder = &MockOffsetApplicationMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOffsetApplication) EXPECT() *MockOffsetApplicationMockRecorder {
+	return m.recorder
+}
+
+// AddNoOp mocks base method.
+func (m *MockOffsetApplication) AddNoOp(key maroon.OffsetKey) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddNoOp", key)
+}
+
+// AddNoOp indicates an expected call of AddNoOp.
+func (mr *MockOffsetApplicationMockRecorder) AddNoOp(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNoOp", reflect.TypeOf((*MockOffsetApplication)(nil).AddNoOp), key)
+}
+
+// AddTx mocks base method.
+func (m *MockOffsetApplication) AddTx(key maroon.OffsetKey, payload []byte) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddTx", key, payload)
+}
+
+// AddTx indicates an expected call of AddTx.
+func (mr *MockOffsetApplicationMockRecorder) AddTx(key, payload any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddTx", reflect.TypeOf((*MockOffsetApplication)(nil).AddTx), key, payload)
+}
+
+// Demoted mocks base method.
+func (m *MockOffsetApplication) Demoted() <-chan int64 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Demoted")
+	ret0, _ := ret[0].(<-chan int64)
+	return ret0
+}
+
+// Demoted indicates an expected call of Demoted.
+func (mr *MockOffsetApplicationMockRecorder) Demoted() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Demoted", reflect.TypeOf((*MockOffsetApplication)(nil).Demoted))
+}
+
+// Run mocks base method.
+func (m *MockOffsetApplication) Run(isLeaderCh <-chan maroon.Leadership, vectorWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Run", isLeaderCh, vectorWatchCh, stopCh)
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockOffsetApplicationMockRecorder) Run(isLeaderCh, vectorWatchCh, stopCh any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockOffsetApplication)(nil).Run), isLeaderCh, vectorWatchCh, stopCh)
+}
@@ -0,0 +1,360 @@
+package maroon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// how often the node publishes its local vector
+// and the leader publishes the majority to etcd
+const vectorPublishInterval = 200 * time.Millisecond
+
+// peer vector that wasn't updated for that long doesn't count for the majority
+// the node is most likely gone
+const peerVectorTTL = 10 * vectorPublishInterval
+
+// offset vector protocol from doc/communication-gateway-maroon.md
+// alternative to the per transaction hashing of the application
+type offsetApplication struct {
+	// TODO: get rid of locks
+	mu sync.Mutex
+
+	// transactions that are not applied yet
+	txs map[OffsetKey]p2p.OffsetTx
+
+	// what this node has stored
+	uncommittedLocal OffsetVector
+	// leader only: the last vectors published by other nodes
+	peerVectors map[string]peerVector
+
+	// the latest record from etcd
+	committed OffsetVector
+	// commit steps from etcd that are not applied yet, in seq order
+	pendingSteps []CommittedRecord
+	// the last step that was handed over to committedCh
+	applied    OffsetVector
+	appliedSeq uint64
+	// leader only: seq of the last record put into etcd
+	publishedSeq uint64
+
+	isLeader bool
+	// create revision of the leader key, guards leader writes
+	leaderRev   int64
+	demotedCh   chan int64
+	clusterSize int
+
+	cli         ETCD
+	transport   VectorTransport
+	committedCh chan CommittedTx
+}
+
+type peerVector struct {
+	vector OffsetVector
+	at     time.Time
+}
+
+// transaction with its place in the global order
+type CommittedTx struct {
+	Key     OffsetKey
+	Payload []byte
+}
+
+// clusterSize - amount of nodes including this one, majority is counted out of it
+// committed transactions come out of the channel in the same order on every node
+func NewOffsetApp(cli ETCD, transport VectorTransport, clusterSize int) (*offsetApplication, <-chan CommittedTx) {
+	committedCh := make(chan CommittedTx, 1024)
+	return &offsetApplication{
+		txs:              make(map[OffsetKey]p2p.OffsetTx),
+		uncommittedLocal: make(OffsetVector),
+		peerVectors:      make(map[string]peerVector),
+		committed:        make(OffsetVector),
+		applied:          make(OffsetVector),
+		clusterSize:      clusterSize,
+		cli:              cli,
+		transport:        transport,
+		committedCh:      committedCh,
+		demotedCh:        make(chan int64, 1),
+	}, committedCh
+}
+
+func (a *offsetApplication) Run(isLeaderCh <-chan Leadership, vectorWatchCh clientv3.WatchChan, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(vectorPublishInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case l := <-isLeaderCh:
+			a.mu.Lock()
+			a.isLeader = l.IsLeader
+			a.leaderRev = l.Revision
+			a.mu.Unlock()
+		case <-ticker.C:
+			a.publish()
+			// missing transactions could have come with gossip
+			a.applyCommitted()
+		case newEvent, ok := <-vectorWatchCh:
+			if !ok {
+				logger.Errorf(logger.Application, "etcd vector watch channel is closed")
+				vectorWatchCh = nil
+				continue
+			}
+			for _, ev := range newEvent.Events {
+				if ev.Type != clientv3.EventTypePut {
+					continue
+				}
+				rec, err := DecodeCommittedRecord(ev.Kv.Value)
+				if err != nil {
+					logger.Errorf(logger.Application, "skip committed vector: %v", err)
+					continue
+				}
+				a.commit(rec, ev.Kv.ModRevision)
+			}
+			a.applyCommitted()
+		}
+	}
+}
+
+func (a *offsetApplication) Demoted() <-chan int64 {
+	return a.demotedCh
+}
+
+// transaction from the gateway
+// stores it and spreads it to the other nodes
+func (a *offsetApplication) AddTx(key OffsetKey, payload []byte) {
+	a.add(p2p.OffsetTx{RangeIndex: key.RangeIndex, Offset: key.Offset, Payload: payload})
+}
+
+// offset the gateway is never going to use
+// without it the vector never goes past the offset
+func (a *offsetApplication) AddNoOp(key OffsetKey) {
+	a.add(p2p.OffsetTx{RangeIndex: key.RangeIndex, Offset: key.Offset, NoOp: true})
+}
+
+func (a *offsetApplication) add(tx p2p.OffsetTx) {
+	a.store([]p2p.OffsetTx{tx})
+	a.transport.GossipOffsetTxs([]p2p.OffsetTx{tx})
+}
+
+// p2p.OffsetStore
+func (a *offsetApplication) StoreOffsetTxs(txs []p2p.OffsetTx) error {
+	a.store(txs)
+	return nil
+}
+
+// p2p.OffsetStore
+func (a *offsetApplication) ObserveVector(nodeID string, vector map[uint64]uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.peerVectors[nodeID] = peerVector{vector: OffsetVector(vector), at: time.Now()}
+}
+
+func (a *offsetApplication) store(txs []p2p.OffsetTx) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	touched := make(map[uint64]bool)
+	for _, tx := range txs {
+		if tx.Offset < a.applied[tx.RangeIndex] {
+			// already applied
+			continue
+		}
+		key := OffsetKey{RangeIndex: tx.RangeIndex, Offset: tx.Offset}
+		if prev, ok := a.txs[key]; ok && !prev.NoOp && tx.NoOp {
+			// the gateway thought it wasn't delivered, the real one wins
+			continue
+		}
+		a.txs[key] = tx
+		touched[tx.RangeIndex] = true
+	}
+
+	// local vector moves only when there are no gaps
+	for r := range touched {
+		for {
+			next := a.uncommittedLocal[r]
+			if _, ok := a.txs[OffsetKey{RangeIndex: r, Offset: next}]; !ok {
+				break
+			}
+			a.uncommittedLocal[r] = next + 1
+		}
+	}
+}
+
+// publishes local vector to the peers
+// leader also computes the majority and puts it into etcd
+func (a *offsetApplication) publish() {
+	a.mu.Lock()
+	local := a.uncommittedLocal.Copy()
+	if !a.isLeader {
+		a.mu.Unlock()
+		a.transport.BroadcastVector(local)
+		return
+	}
+
+	lastSeq := a.appliedSeq + uint64(len(a.pendingSteps))
+	if a.publishedSeq > lastSeq {
+		// wait until the previous record comes back from etcd
+		a.mu.Unlock()
+		return
+	}
+
+	vectors := []OffsetVector{local}
+	for id, v := range a.peerVectors {
+		if time.Since(v.at) > peerVectorTTL {
+			logger.Infof(logger.Application, "vector of %v is expired", id)
+			delete(a.peerVectors, id)
+			continue
+		}
+		vectors = append(vectors, v.vector)
+	}
+	// majority can go back if some node restarted and lost its state
+	// but what is committed stays committed
+	majority := Majority(vectors, a.clusterSize).Merge(a.committed)
+	if !majority.Ahead(a.committed) {
+		a.mu.Unlock()
+		return
+	}
+	rec := CommittedRecord{
+		Seq:    lastSeq + 1,
+		Vector: majority,
+	}
+	a.publishedSeq = rec.Seq
+	leaderRev := a.leaderRev
+	a.mu.Unlock()
+
+	data, err := rec.Encode()
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to encode committed vector: %v", err)
+		return
+	}
+	_, err = fencedPut(context.TODO(), a.cli, leaderRev, VectorKey, string(data))
+	if errors.Is(err, ErrFenced) {
+		a.mu.Lock()
+		demote(&a.isLeader, leaderRev, a.demotedCh, err)
+		a.mu.Unlock()
+		return
+	}
+	if err != nil {
+		logger.Errorf(logger.Application, "failed to put committed vector: %v", err)
+		return
+	}
+	logger.Infof(logger.Application, "committed vector %d: %v", rec.Seq, rec.Vector)
+}
+
+// new record from etcd, rev is its mod revision
+// steps are never merged over a gap, the order of transactions depends on them
+// called from Run only
+func (a *offsetApplication) commit(rec CommittedRecord, rev int64) {
+	a.mu.Lock()
+	last := a.appliedSeq + uint64(len(a.pendingSteps))
+	a.mu.Unlock()
+	if rec.Seq <= last {
+		return
+	}
+
+	steps := []CommittedRecord{rec}
+	if rec.Seq != last+1 {
+		logger.Warningf(logger.Application, "committed vector %d doesn't follow %d, fetch the missing ones", rec.Seq, last)
+		missing, err := a.fetchSteps(last, rev)
+		if err != nil {
+			// the next record tries again
+			logger.Errorf(logger.Application, "failed to fetch committed vectors after %d: %v", last, err)
+			return
+		}
+		if uint64(len(missing)) != rec.Seq-last-1 {
+			logger.Errorf(logger.Application, "committed vector %d doesn't follow the ones in etcd", rec.Seq)
+			return
+		}
+		steps = append(missing, rec)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, step := range steps {
+		a.pendingSteps = append(a.pendingSteps, step)
+		a.committed = a.committed.Merge(step.Vector)
+	}
+}
+
+// records after seq `after` that were put before revision rev, in seq order
+// they are read from the etcd history of VectorKey
+// TODO: history is compacted at some point, a snapshot of the applied state is needed then
+func (a *offsetApplication) fetchSteps(after uint64, rev int64) ([]CommittedRecord, error) {
+	var res []CommittedRecord
+	// revision 0 would be the latest one
+	for rev > 1 {
+		resp, err := a.cli.Get(context.TODO(), VectorKey, clientv3.WithRev(rev-1))
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Kvs) == 0 {
+			break
+		}
+		rec, err := DecodeCommittedRecord(resp.Kvs[0].Value)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Seq <= after {
+			break
+		}
+		res = append(res, rec)
+		rev = resp.Kvs[0].ModRevision
+	}
+	slices.Reverse(res)
+
+	for i, rec := range res {
+		if rec.Seq != after+uint64(i)+1 {
+			return nil, fmt.Errorf("committed vector %d is missing in etcd", after+uint64(i)+1)
+		}
+	}
+	return res, nil
+}
+
+// hands over committed transactions to committedCh
+// a step is applied only when all its payloads are here, otherwise the order would differ between nodes
+func (a *offsetApplication) applyCommitted() {
+	var ready []CommittedTx
+
+	a.mu.Lock()
+	for len(a.pendingSteps) > 0 {
+		step := a.pendingSteps[0]
+		keys := orderedKeys(a.applied, step.Vector)
+
+		complete := true
+		for _, key := range keys {
+			if _, ok := a.txs[key]; !ok {
+				complete = false
+				break
+			}
+		}
+		if !complete {
+			// TODO: request missing txs from the peers instead of waiting for gossip
+			logger.Debugf(logger.Application, "committed vector %d waits for transactions", step.Seq)
+			break
+		}
+
+		for _, key := range keys {
+			if tx := a.txs[key]; !tx.NoOp {
+				ready = append(ready, CommittedTx{Key: key, Payload: tx.Payload})
+			}
+			delete(a.txs, key)
+		}
+		a.applied = a.applied.Merge(step.Vector)
+		a.appliedSeq = step.Seq
+		a.pendingSteps = a.pendingSteps[1:]
+	}
+	a.mu.Unlock()
+
+	for _, tx := range ready {
+		a.committedCh <- tx
+	}
+}
@@ -0,0 +1,225 @@
+package maroon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type vectorTransportMock struct {
+	mu       sync.Mutex
+	gossiped []p2p.OffsetTx
+}
+
+func (v *vectorTransportMock) GossipOffsetTxs(txs []p2p.OffsetTx) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.gossiped = append(v.gossiped, txs...)
+}
+
+func (v *vectorTransportMock) BroadcastVector(vector map[uint64]uint64) {}
+
+func vectorEvent(t *testing.T, rec CommittedRecord) clientv3.WatchResponse {
+	data, err := rec.Encode()
+	require.NoError(t, err)
+	return clientv3.WatchResponse{
+		Events: []*clientv3.Event{
+			{
+				Type: clientv3.EventTypePut,
+				Kv:   &mvccpb.KeyValue{Key: []byte(VectorKey), Value: data},
+			},
+		},
+	}
+}
+
+func TestLeaderPublishesMajority(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	transport := &vectorTransportMock{}
+
+	app, _ := NewOffsetApp(etcd, transport, 3)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+	go app.Run(isLeaderCh, make(clientv3.WatchChan), stopCh)
+	isLeaderCh <- leadership
+
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 0}, []byte("a"))
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 1}, []byte("b"))
+	// gap, local vector stays at 2
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 3}, []byte("d"))
+	app.ObserveVector("n2", map[uint64]uint64{1: 1})
+	app.ObserveVector("n3", map[uint64]uint64{1: 5, 2: 1})
+
+	var published []byte
+	require.Eventually(t, func() bool {
+		resp, err := etcd.Get(context.Background(), VectorKey)
+		require.NoError(t, err)
+		if len(resp.Kvs) == 0 {
+			return false
+		}
+		published = resp.Kvs[0].Value
+		return true
+	}, time.Second, 10*time.Millisecond, "majority is not published")
+	stopCh <- struct{}{}
+
+	rec, err := DecodeCommittedRecord(published)
+	require.NoError(t, err)
+	require.Equal(t, CommittedRecord{Seq: 1, Vector: OffsetVector{1: 2}}, rec)
+
+	require.Len(t, transport.gossiped, 3)
+}
+
+func TestFollowerAppliesCommittedInOrder(t *testing.T) {
+	app, committedCh := NewOffsetApp(etcdmock.New(), &vectorTransportMock{}, 3)
+	watchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	go app.Run(make(chan Leadership), watchCh, stopCh)
+
+	require.NoError(t, app.StoreOffsetTxs([]p2p.OffsetTx{
+		{RangeIndex: 2, Offset: 0, Payload: []byte("2-0")},
+		{RangeIndex: 1, Offset: 0, Payload: []byte("1-0")},
+		{RangeIndex: 1, Offset: 1, Payload: []byte("1-1")},
+	}))
+
+	watchCh <- vectorEvent(t, CommittedRecord{Seq: 1, Vector: OffsetVector{1: 1, 2: 1}})
+	// step 2 can't be applied until 1-2 arrives
+	watchCh <- vectorEvent(t, CommittedRecord{Seq: 2, Vector: OffsetVector{1: 3, 2: 1}})
+
+	readN := func(n int) []string {
+		var order []string
+		for len(order) < n {
+			select {
+			case tx := <-committedCh:
+				order = append(order, string(tx.Payload))
+			case <-time.After(time.Second):
+				t.Fatalf("got only %v", order)
+			}
+		}
+		return order
+	}
+	require.Equal(t, []string{"1-0", "2-0"}, readN(2))
+
+	select {
+	case tx := <-committedCh:
+		t.Fatalf("step 2 is applied without 1-2: %v", tx)
+	case <-time.After(2 * vectorPublishInterval):
+	}
+
+	require.NoError(t, app.StoreOffsetTxs([]p2p.OffsetTx{{RangeIndex: 1, Offset: 2, Payload: []byte("1-2")}}))
+	require.Equal(t, []string{"1-1", "1-2"}, readN(2))
+	stopCh <- struct{}{}
+}
+
+func TestFencedOffsetLeaderIsDemoted(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	app, _ := NewOffsetApp(etcd, &vectorTransportMock{}, 1)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+	go app.Run(isLeaderCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+
+	// somebody else took the leadership in between
+	leadership.Revision--
+	isLeaderCh <- leadership
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 0}, []byte("a"))
+
+	select {
+	case rev := <-app.Demoted():
+		require.Equal(t, leadership.Revision, rev)
+	case <-time.After(time.Second):
+		t.Fatal("leader is not demoted")
+	}
+	resp, err := etcd.Get(context.Background(), VectorKey)
+	require.NoError(t, err)
+	require.Empty(t, resp.Kvs)
+}
+
+func TestNoOpFillsOffset(t *testing.T) {
+	app, committedCh := NewOffsetApp(etcdmock.New(), &vectorTransportMock{}, 3)
+	watchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	go app.Run(make(chan Leadership), watchCh, stopCh)
+	defer close(stopCh)
+
+	app.AddNoOp(OffsetKey{RangeIndex: 1, Offset: 0})
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 1}, []byte("1-1"))
+	// the real payload came after all, the no-op doesn't replace it
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 2}, []byte("1-2"))
+	app.AddNoOp(OffsetKey{RangeIndex: 1, Offset: 2})
+
+	app.mu.Lock()
+	require.Equal(t, OffsetVector{1: 3}, app.uncommittedLocal)
+	app.mu.Unlock()
+
+	watchCh <- vectorEvent(t, CommittedRecord{Seq: 1, Vector: OffsetVector{1: 3}})
+	for _, payload := range []string{"1-1", "1-2"} {
+		select {
+		case tx := <-committedCh:
+			require.Equal(t, payload, string(tx.Payload))
+		case <-time.After(time.Second):
+			t.Fatalf("%v is not applied", payload)
+		}
+	}
+}
+
+func TestFollowerFetchesMissingSteps(t *testing.T) {
+	etcd := etcdmock.New()
+	app, committedCh := NewOffsetApp(etcd, &vectorTransportMock{}, 3)
+	watchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	go app.Run(make(chan Leadership), watchCh, stopCh)
+	defer close(stopCh)
+
+	require.NoError(t, app.StoreOffsetTxs([]p2p.OffsetTx{
+		{RangeIndex: 1, Offset: 0, Payload: []byte("1-0")},
+		{RangeIndex: 2, Offset: 0, Payload: []byte("2-0")},
+		{RangeIndex: 1, Offset: 1, Payload: []byte("1-1")},
+	}))
+
+	// only the last one comes through the watch
+	var last clientv3.WatchResponse
+	for _, rec := range []CommittedRecord{
+		{Seq: 1, Vector: OffsetVector{2: 1}},
+		{Seq: 2, Vector: OffsetVector{1: 1, 2: 1}},
+		{Seq: 3, Vector: OffsetVector{1: 2, 2: 1}},
+	} {
+		last = vectorEvent(t, rec)
+		resp, err := etcd.Put(context.Background(), VectorKey, string(last.Events[0].Kv.Value))
+		require.NoError(t, err)
+		last.Events[0].Kv.ModRevision = resp.Header.Revision
+	}
+	watchCh <- last
+
+	// 2-0 goes first, it's committed by the first step
+	for _, payload := range []string{"2-0", "1-0", "1-1"} {
+		select {
+		case tx := <-committedCh:
+			require.Equal(t, payload, string(tx.Payload))
+		case <-time.After(time.Second):
+			t.Fatalf("%v is not applied", payload)
+		}
+	}
+}
+
+func TestPeerVectorsExpire(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	app, _ := NewOffsetApp(etcd, &vectorTransportMock{}, 3)
+	app.isLeader, app.leaderRev = true, leadership.Revision
+
+	app.AddTx(OffsetKey{RangeIndex: 1, Offset: 0}, []byte("a"))
+	app.ObserveVector("n2", map[uint64]uint64{1: 1})
+	app.peerVectors["n2"] = peerVector{vector: OffsetVector{1: 1}, at: time.Now().Add(-2 * peerVectorTTL)}
+
+	// only this node has it, n2 is gone
+	app.publish()
+	resp, err := etcd.Get(context.Background(), VectorKey)
+	require.NoError(t, err)
+	require.Empty(t, resp.Kvs)
+	require.Empty(t, app.peerVectors)
+}
@@ -1,24 +1,123 @@
 package maroon
 
 import (
+	"bytes"
 	"crypto/sha256"
-	"encoding/json"
+	"errors"
 	"fmt"
 
-	"github.com/akantsevoi/test-environment/pkg/logger"
+	operationv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/operation/v1"
+	"google.golang.org/protobuf/proto"
 )
 
-func (o *Operation) Hash() string {
-	hash, _ := o.HashBin()
+// version of OperationEnvelope.payload, see doc/operation-encoding.md
+const operationEncodingV1 = 1
+
+var ErrBadOperation = errors.New("bad operation")
 
+// proto3 never writes zero values and go protobuf writes fields in the field number order
+// deterministic only matters for maps, but it costs nothing and keeps it that way
+var canonical = proto.MarshalOptions{Deterministic: true}
+
+// hash of the encoded operation
+// operations inside the node were encoded at least once, so for them it can't fail
+// empty for the ones that can't be encoded, see HashBin
+func (o *Operation) Hash() string {
+	hash, _, err := o.HashBin()
+	if err != nil {
+		return ""
+	}
 	return hash
 }
 
-func (o *Operation) HashBin() (string, []byte) {
-	message, err := json.Marshal(o)
+// hash and the encoded operation that goes over the wire
+func (o *Operation) HashBin() (string, []byte, error) {
+	message, err := o.Encode()
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(message)), message, nil
+}
+
+// encoded OperationEnvelope
+func (o *Operation) Encode() ([]byte, error) {
+	payload, err := canonical.Marshal(&operationv1.Operation{
+		Type:     int64(o.OpType),
+		Value:    o.Value,
+		ClientId: o.ClientID,
+		Seq:      o.Seq,
+		Nonce:    o.Nonce,
+	})
 	if err != nil {
-		logger.Fatalf(logger.Application, "Failed to marshal operation: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrBadOperation, err)
 	}
+	message, err := canonical.Marshal(&operationv1.OperationEnvelope{
+		Version: operationEncodingV1,
+		Payload: payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadOperation, err)
+	}
+	return message, nil
+}
 
-	return fmt.Sprintf("%x", sha256.Sum256(message)), message
+// decodes operation received from the other node and checks that it matches the hash
+func decodeOperation(hash string, message []byte) (Operation, error) {
+	if h := fmt.Sprintf("%x", sha256.Sum256(message)); h != hash {
+		return Operation{}, fmt.Errorf("operation hash mismatch: expected %v got %v", hash, h)
+	}
+
+	var envelope operationv1.OperationEnvelope
+	if err := proto.Unmarshal(message, &envelope); err != nil {
+		return Operation{}, fmt.Errorf("failed to decode operation %v: %w", hash, err)
+	}
+	if envelope.Version != operationEncodingV1 {
+		return Operation{}, fmt.Errorf("operation %v: unknown encoding version %d", hash, envelope.Version)
+	}
+	var op operationv1.Operation
+	if err := proto.Unmarshal(envelope.Payload, &op); err != nil {
+		return Operation{}, fmt.Errorf("failed to decode operation %v: %w", hash, err)
+	}
+	// fields of newer versions are dropped here, the envelope is kept by keepRaw
+	return Operation{
+		OpType:   OperationType(op.Type),
+		Value:    op.Value,
+		ClientID: op.ClientId,
+		Seq:      op.Seq,
+		Nonce:    op.Nonce,
+	}, nil
+}
+
+// operation with fields of a newer version isn't encoded back to the same bytes
+// so its envelope is kept and served to the peers as it came
+// should be called under opMU
+func (a *application) keepRaw(id string, op Operation, message []byte) {
+	if encoded, err := op.Encode(); err == nil && bytes.Equal(encoded, message) {
+		return
+	}
+	a.rawOps[id] = message
+}
+
+// envelopes of the operations that were kept by keepRaw, nil if there are none
+// should be called under opMU
+func (a *application) rawOf(ids ...string) map[string][]byte {
+	var res map[string][]byte
+	for _, id := range ids {
+		if raw, ok := a.rawOps[id]; ok {
+			if res == nil {
+				res = make(map[string][]byte)
+			}
+			res[id] = raw
+		}
+	}
+	return res
+}
+
+// the envelope the operation came in
+// should be called under opMU
+func (a *application) encodedOp(id string, op Operation) ([]byte, error) {
+	if raw, ok := a.rawOps[id]; ok {
+		return raw, nil
+	}
+	return op.Encode()
 }
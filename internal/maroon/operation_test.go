@@ -0,0 +1,103 @@
+package maroon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	operationv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/operation/v1"
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/akantsevoi/test-environment/pkg/wal"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// hashes are stored in etcd and wal, the encoding can't change silently
+func TestOperationEncodingIsStable(t *testing.T) {
+	op := Operation{OpType: KVPut, Value: "a"}
+	message, err := op.Encode()
+	require.NoError(t, err)
+	// envelope{version: 1, payload: {type: 1, value: "a"}}
+	require.Equal(t, "080112050801120161", hex.EncodeToString(message))
+
+	// request fields go after the old ones only when they're set
+	op.ClientID, op.Seq = "c", 2
+	message, err = op.Encode()
+	require.NoError(t, err)
+	require.Equal(t, "0801120a08011201611a01632002", hex.EncodeToString(message))
+}
+
+func TestDecodeOperation(t *testing.T) {
+	op := Operation{OpType: KVCAS, Value: `{"key":"a"}`, ClientID: "gw-1", Seq: 7}
+	hash, message, err := op.HashBin()
+	require.NoError(t, err)
+
+	decoded, err := decodeOperation(hash, message)
+	require.NoError(t, err)
+	require.Equal(t, op, decoded)
+
+	_, err = decodeOperation(strings.Repeat("0", 64), message)
+	require.ErrorContains(t, err, "hash mismatch")
+
+	future, err := proto.Marshal(&operationv1.OperationEnvelope{Version: 2, Payload: []byte{1}})
+	require.NoError(t, err)
+	_, err = decodeOperation(fmt.Sprintf("%x", sha256.Sum256(future)), future)
+	require.ErrorContains(t, err, "unknown encoding version 2")
+}
+
+func TestBrokenOperationIsRejected(t *testing.T) {
+	op := Operation{OpType: KVPut, Value: "\xff"}
+	_, _, err := op.HashBin()
+	require.ErrorIs(t, err, ErrBadOperation)
+	require.Empty(t, op.Hash())
+
+	etcd, leadership := leaderETCD(t)
+	app := New(etcd, &servMock{})
+	app.isLeader, app.leaderRev = true, leadership.Revision
+	_, err = app.AddOp(op)
+	require.ErrorIs(t, err, ErrBadOperation)
+}
+
+// a node of the newer version sent an operation with a field this one doesn't know
+func TestNewerFieldsAreServedUnchanged(t *testing.T) {
+	payload, err := proto.Marshal(&operationv1.Operation{Type: int64(KVPut), Value: "a"})
+	require.NoError(t, err)
+	payload = protowire.AppendTag(payload, 100, protowire.BytesType)
+	payload = protowire.AppendString(payload, "new")
+	message, err := proto.Marshal(&operationv1.OperationEnvelope{Version: operationEncodingV1, Payload: payload})
+	require.NoError(t, err)
+	id := fmt.Sprintf("%x", sha256.Sum256(message))
+	tx := p2p.Transaction{ID: id, TxData: message}
+
+	dir := t.TempDir()
+	w, err := wal.Open(dir)
+	require.NoError(t, err)
+	app := New(etcdmock.New(), &servMock{}, WithWAL(w))
+	require.NoError(t, app.StoreTx(tx))
+	require.Equal(t, []p2p.Transaction{tx}, app.GetTxs([]string{id}))
+	require.Equal(t, []p2p.Transaction{tx}, app.PendingTxs())
+	require.NoError(t, w.Close())
+
+	w, err = wal.Open(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	restarted := New(etcdmock.New(), &servMock{}, WithWAL(w))
+	require.NoError(t, restarted.Recover())
+	require.Equal(t, []p2p.Transaction{tx}, restarted.PendingTxs())
+
+	block, err := newBlock(0, "", []string{id})
+	require.NoError(t, err)
+	ops, err := restarted.collectBlockOps(context.Background(), block)
+	require.NoError(t, err)
+	blockHash, err := block.Hash()
+	require.NoError(t, err)
+	restarted.opMU.Lock()
+	restarted.applyBlock(block, blockHash, ops)
+	restarted.opMU.Unlock()
+	require.Equal(t, []p2p.Transaction{tx}, restarted.GetTxs([]string{id}))
+}
@@ -0,0 +1,42 @@
+package maroon
+
+type Option func(*application)
+
+// operations and blocks are written to the log before they're acked or applied
+// by default the state is only in memory
+func WithWAL(wal WAL) Option {
+	return func(a *application) {
+		a.wal = wal
+	}
+}
+
+// committed operations are applied to it in block order
+// by default it's DefaultRegistry
+func WithStateMachine(sm StateMachine) Option {
+	return func(a *application) {
+		a.sm = sm
+	}
+}
+
+// snapshot is taken every n applied blocks, 0 - never
+func WithSnapshotEvery(n int64) Option {
+	return func(a *application) {
+		a.snapshotEvery = n
+	}
+}
+
+// when the leader seals acked operations into a block
+// by default it's DefaultSealPolicy
+func WithSealPolicy(p SealPolicy) Option {
+	return func(a *application) {
+		a.seal = p
+	}
+}
+
+// retries of client requests are recognized during that many blocks
+// after the request is applied, 0 - no deduplication
+func WithDedupWindow(blocks int64) Option {
+	return func(a *application) {
+		a.dedup = newDedupTable(blocks)
+	}
+}
@@ -0,0 +1,134 @@
+package maroon
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+// how long the new leader waits for the followers' pending transactions
+const recoveryTimeout = 5 * time.Second
+
+// p2p.TxStore
+// operations the node acked (or got as a leader) that are not in any block yet
+func (a *application) PendingTxs() []p2p.Transaction {
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+
+	res := make([]p2p.Transaction, 0, len(a.inFlyOPs)+len(a.receivedOps))
+	for _, ops := range []map[string]Operation{a.inFlyOPs, a.receivedOps} {
+		for id, op := range ops {
+			message, err := a.encodedOp(id, op)
+			if err != nil {
+				logger.Errorf(logger.Application, "failed to encode op %v: %v", id, err)
+				continue
+			}
+			res = append(res, p2p.Transaction{ID: id, TxData: message})
+		}
+	}
+	return res
+}
+
+// retries until it succeeds or the node isn't the leader of that term anymore
+func (a *application) fetchPendingTxs(ctx context.Context, leaderRev int64) ([]p2p.Transaction, bool) {
+	backoff := minFetchBackoff
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, recoveryTimeout)
+		txs, err := a.p2pDistr.FetchPendingTxs(fetchCtx)
+		cancel()
+		if err == nil {
+			return txs, true
+		}
+		logger.Errorf(logger.Application, "failed to collect pending txs, retry in %v: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxFetchBackoff)
+
+		a.opMU.Lock()
+		leader := a.isLeader && a.leaderRev == leaderRev
+		a.opMU.Unlock()
+		if !leader {
+			return nil, false
+		}
+	}
+}
+
+// new leader side
+// operations of the previous leader could've been acked by the followers
+// but never got into a block, so they are proposed again
+// every leader goes through them in the same order: sorted by id
+func (a *application) recoverPending(ctx context.Context, leaderRev int64) {
+	txs, ok := a.fetchPendingTxs(ctx, leaderRev)
+	if !ok {
+		return
+	}
+
+	pending := make(map[string]Operation)
+	messages := make(map[string][]byte)
+	for _, tx := range txs {
+		op, err := decodeOperation(tx.ID, tx.TxData)
+		if err != nil {
+			logger.Errorf(logger.Application, "got broken pending tx from peer: %v", err)
+			continue
+		}
+		pending[tx.ID] = op
+		messages[tx.ID] = tx.TxData
+	}
+
+	a.opMU.Lock()
+	if !a.isLeader || a.leaderRev != leaderRev {
+		a.opMU.Unlock()
+		return
+	}
+	for id, op := range pending {
+		a.keepRaw(id, op, messages[id])
+	}
+	for id, op := range a.receivedOps {
+		pending[id] = op
+	}
+	ids := make([]string, 0, len(pending))
+	for id := range pending {
+		if _, ok := a.confirmedIdx[id]; ok {
+			continue
+		}
+		if _, ok := a.inFlyOPs[id]; ok {
+			// proposed already
+			continue
+		}
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	var reproposed []p2p.Transaction
+	for _, id := range ids {
+		op := pending[id]
+		message, err := a.encodedOp(id, op)
+		if err != nil {
+			logger.Errorf(logger.Application, "failed to encode recovered op %v: %v", id, err)
+			continue
+		}
+		if err := a.logRecord(walRecord{Kind: walOp, ID: id, Op: &op, Leader: true, Raw: a.rawOf(id)}); err != nil {
+			logger.Errorf(logger.Application, "failed to log recovered op %v: %v", id, err)
+			continue
+		}
+		a.inFlyOPs[id] = op
+		delete(a.receivedOps, id)
+		reproposed = append(reproposed, p2p.Transaction{ID: id, TxData: message})
+	}
+	a.opMU.Unlock()
+
+	logger.Infof(logger.Application, "recovery: %d pending ops are proposed again", len(reproposed))
+	for _, tx := range reproposed {
+		if err := a.p2pDistr.DistributeTx(tx); err != nil {
+			// stays in fly, a client retry distributes it again
+			logger.Errorf(logger.Application, "failed to distribute recovered op %v: %v", tx.ID, err)
+		}
+	}
+}
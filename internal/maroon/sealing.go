@@ -0,0 +1,72 @@
+package maroon
+
+import (
+	"expvar"
+	"time"
+)
+
+// why the block was sealed
+const (
+	sealByOps    = "ops"
+	sealByBytes  = "bytes"
+	sealByLinger = "linger"
+)
+
+// amount of sealed blocks by the reason
+var sealedBlocks = expvar.NewMap("maroon_sealed_blocks")
+
+// when the leader puts acked operations into a block
+// limits that are 0 are not checked
+type SealPolicy struct {
+	// block is sealed as soon as there are that many acked operations
+	MaxOps int
+	// block is sealed as soon as acked operation values take that many bytes
+	// a block never goes over it unless a single operation is bigger
+	MaxBytes int
+	// partial block is sealed when the oldest acked operation waits that long
+	Linger time.Duration
+}
+
+func DefaultSealPolicy() SealPolicy {
+	return SealPolicy{
+		MaxOps: 3,
+		Linger: 100 * time.Millisecond,
+	}
+}
+
+// how many of the acked operations go into the next block and why
+// 0 - nothing to seal yet
+func (p SealPolicy) take(ops []Operation, waited time.Duration) (int, string) {
+	n := len(ops)
+	if n == 0 {
+		return 0, ""
+	}
+
+	var reason string
+	if p.MaxOps > 0 && n >= p.MaxOps {
+		n, reason = p.MaxOps, sealByOps
+	}
+	if p.MaxBytes > 0 {
+		size := 0
+		for i, op := range ops[:n] {
+			size += len(op.Value)
+			if size > p.MaxBytes {
+				// the one that doesn't fit waits for the next block
+				n, reason = max(i, 1), sealByBytes
+				break
+			}
+			if size == p.MaxBytes {
+				n, reason = i+1, sealByBytes
+				break
+			}
+		}
+	}
+	if reason == "" && p.Linger > 0 && waited >= p.Linger {
+		reason = sealByLinger
+	}
+
+	if reason == "" {
+		return 0, ""
+	}
+	return n, reason
+}
@@ -0,0 +1,101 @@
+package maroon
+
+import (
+	"context"
+	"expvar"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func sizedOps(sizes ...int) []Operation {
+	var res []Operation
+	for i, size := range sizes {
+		res = append(res, Operation{OpType: PrintTimestamp, Value: strings.Repeat("x", size), Nonce: uint64(i) + 1})
+	}
+	return res
+}
+
+func sealedCount(reason string) int64 {
+	v, ok := sealedBlocks.Get(reason).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestSealPolicyTake(t *testing.T) {
+	p := SealPolicy{MaxOps: 3, MaxBytes: 10, Linger: time.Second}
+
+	n, _ := p.take(sizedOps(1, 1), 0)
+	require.Zero(t, n)
+
+	n, reason := p.take(sizedOps(1, 1, 1, 1), 0)
+	require.Equal(t, 3, n)
+	require.Equal(t, sealByOps, reason)
+
+	// the third one doesn't fit
+	n, reason = p.take(sizedOps(4, 4, 4), 0)
+	require.Equal(t, 2, n)
+	require.Equal(t, sealByBytes, reason)
+
+	n, reason = p.take(sizedOps(5, 5), 0)
+	require.Equal(t, 2, n)
+	require.Equal(t, sealByBytes, reason)
+
+	// too big for any block, goes alone
+	n, reason = p.take(sizedOps(20, 1), 0)
+	require.Equal(t, 1, n)
+	require.Equal(t, sealByBytes, reason)
+
+	n, reason = p.take(sizedOps(1), time.Second)
+	require.Equal(t, 1, n)
+	require.Equal(t, sealByLinger, reason)
+
+	// no limits - waits forever
+	n, _ = SealPolicy{}.take(sizedOps(1, 1, 1, 1), time.Hour)
+	require.Zero(t, n)
+}
+
+func TestLingerSealsPartialBlock(t *testing.T) {
+	etcd, leadership := leaderETCD(t)
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	app := New(etcd, &servMock{distr: func(tx p2p.Transaction) {}},
+		WithSealPolicy(SealPolicy{MaxOps: 10, Linger: 20 * time.Millisecond}))
+	go app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+	defer close(stopCh)
+	isLeaderCh <- leadership
+
+	before := sealedCount(sealByLinger)
+	op := Operation{OpType: PrintTimestamp, Value: "lonely", Nonce: 1}
+	var ch <-chan OpStatus
+	require.Eventually(t, func() bool {
+		var err error
+		ch, err = app.AddOp(op)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+	opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+
+	var last OpStatus
+	for st := range ch {
+		last = st
+	}
+	require.Equal(t, OpCommitted, last.State)
+	require.Equal(t, int64(0), last.Block)
+
+	resp, err := etcd.Get(context.Background(), blockKey(0))
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	require.NoError(t, err)
+	require.Equal(t, []string{op.Hash()}, block.TxIDs)
+
+	require.Equal(t, before+1, sealedCount(sealByLinger))
+}
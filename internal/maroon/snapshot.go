@@ -0,0 +1,255 @@
+package maroon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// state of the node after the block
+type Snapshot struct {
+	// last block in the snapshot
+	Block     int64  `json:"block"`
+	BlockHash string `json:"blockHash"`
+	Root      string `json:"root"`
+	// amount of operations confirmed up to the block
+	Ops   int    `json:"ops"`
+	State []byte `json:"state"`
+	// recently applied client requests
+	Dedup []dedupRecord `json:"dedup,omitempty"`
+}
+
+func decodeSnapshot(data []byte) (Snapshot, error) {
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// p2p.SnapshotSource
+func (a *application) LatestSnapshot() (int64, []byte, bool) {
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	if a.snapshot == nil {
+		return 0, nil, false
+	}
+	return a.snapshotBlock, a.snapshot, true
+}
+
+// should be called under opMU
+func (a *application) takeSnapshot() error {
+	state, err := a.sm.Snapshot()
+	if err != nil {
+		return err
+	}
+	snap := Snapshot{
+		Block:     a.batchCounter - 1,
+		BlockHash: a.prevBlockHash,
+		Root:      a.prevBlockRoot,
+		Ops:       a.compactedOps + len(a.confirmedOps),
+		State:     state,
+		Dedup:     a.dedup.records(),
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	if err := a.persistSnapshot(snap); err != nil {
+		return err
+	}
+
+	// operations since the previous snapshot stay
+	// so followers that are a bit behind can still fetch them
+	a.compact(a.snapshotOps)
+	a.snapshot, a.snapshotBlock, a.snapshotOps = data, snap.Block, snap.Ops
+	logger.Infof(logger.Application, "snapshot at block %d: %d ops, %d bytes", snap.Block, snap.Ops, len(data))
+	return nil
+}
+
+// snapshot goes to the wal together with the pending operations
+// so everything before it can be dropped
+// should be called under opMU
+func (a *application) persistSnapshot(snap Snapshot) error {
+	if a.wal == nil {
+		return nil
+	}
+	var pending []string
+	for _, ops := range []map[string]Operation{a.inFlyOPs, a.receivedOps} {
+		for id := range ops {
+			pending = append(pending, id)
+		}
+	}
+	index, err := a.appendRecord(walRecord{
+		Kind:     walSnapshot,
+		Snapshot: &snap,
+		InFly:    a.inFlyOPs,
+		Received: a.receivedOps,
+		Acked:    a.ackedHashes,
+		Raw:      a.rawOf(pending...),
+	})
+	if err != nil {
+		return err
+	}
+	return a.wal.TruncateFront(index)
+}
+
+// drops confirmed operations with positions below upTo
+// should be called under opMU
+func (a *application) compact(upTo int) {
+	n := upTo - a.compactedOps
+	if n <= 0 {
+		return
+	}
+	// hash of the operation isn't its id if it was kept by keepRaw
+	for id, pos := range a.confirmedIdx {
+		if pos < upTo {
+			delete(a.confirmedIdx, id)
+			delete(a.rawOps, id)
+		}
+	}
+	a.confirmedOps = slices.Clone(a.confirmedOps[n:])
+	a.compactedOps = upTo
+}
+
+// replaces the whole confirmed state with the snapshot
+// should be called under opMU
+func (a *application) restoreSnapshot(snap Snapshot, data []byte) error {
+	if err := a.sm.Restore(snap.State); err != nil {
+		return err
+	}
+	a.dedup.restore(snap.Dedup)
+	a.confirmedOps = nil
+	a.confirmedIdx = make(map[string]int)
+	// only pending operations are left
+	maps.DeleteFunc(a.rawOps, func(id string, _ []byte) bool {
+		_, inFly := a.inFlyOPs[id]
+		_, received := a.receivedOps[id]
+		return !inFly && !received
+	})
+	a.compactedOps = snap.Ops
+	a.batchCounter = snap.Block + 1
+	a.prevBlockHash = snap.BlockHash
+	a.prevBlockRoot = snap.Root
+	a.snapshot, a.snapshotBlock, a.snapshotOps = data, snap.Block, snap.Ops
+	return nil
+}
+
+// follower that missed blocks takes the state from the peers instead of replaying them
+func (a *application) installSnapshot(ctx context.Context) error {
+	block, data, err := a.p2pDistr.FetchSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	snap, err := decodeSnapshot(data)
+	if err != nil {
+		return err
+	}
+	if snap.Block != block {
+		return fmt.Errorf("peer sent snapshot of block %d as %d", snap.Block, block)
+	}
+	if err := a.checkSnapshot(ctx, snap); err != nil {
+		return err
+	}
+
+	from := a.nextBlock()
+	if snap.Block < from {
+		// nothing new there
+		return nil
+	}
+	covered, err := a.blockTxIDs(ctx, from, snap.Block+1)
+	if err != nil {
+		return err
+	}
+
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	if snap.Block < a.batchCounter {
+		return nil
+	}
+	if err := a.restoreSnapshot(snap, data); err != nil {
+		return err
+	}
+	a.dropCovered(covered)
+	if err := a.persistSnapshot(snap); err != nil {
+		return err
+	}
+	logger.Infof(logger.Application, "installed snapshot of block %d", snap.Block)
+	return nil
+}
+
+// the snapshot has to end with the block that is in etcd
+func (a *application) checkSnapshot(ctx context.Context, snap Snapshot) error {
+	resp, err := a.cli.Get(ctx, blockKey(snap.Block))
+	if err != nil {
+		return fmt.Errorf("failed to get block %d of the snapshot: %w", snap.Block, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return fmt.Errorf("block %d of the snapshot is not in etcd", snap.Block)
+	}
+	block, err := decodeBlock(resp.Kvs[0].Value)
+	if err != nil {
+		return err
+	}
+	blockHash, err := block.Hash()
+	if err != nil {
+		return err
+	}
+	if block.Root != snap.Root || blockHash != snap.BlockHash {
+		return fmt.Errorf("snapshot doesn't match block %d: root %v, hash %v", snap.Block, snap.Root, snap.BlockHash)
+	}
+	return nil
+}
+
+// block number of every operation in the blocks [from, to) in etcd
+func (a *application) blockTxIDs(ctx context.Context, from, to int64) (map[string]int64, error) {
+	ids := make(map[string]int64)
+	for from < to {
+		resp, err := a.cli.Get(ctx, blockKey(from), clientv3.WithRange(blockKey(to)), clientv3.WithLimit(maxPendingBlocks))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blocks [%d, %d): %w", from, to, err)
+		}
+		if len(resp.Kvs) == 0 {
+			break
+		}
+		for _, kv := range resp.Kvs {
+			block, err := decodeBlock(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("block %s: %w", kv.Key, err)
+			}
+			for _, id := range block.TxIDs {
+				ids[id] = block.Number
+			}
+			from = block.Number + 1
+		}
+	}
+	return ids, nil
+}
+
+// pending operations that are in the snapshot already
+// otherwise they are served as pending and a new leader proposes them again
+// should be called under opMU
+func (a *application) dropCovered(covered map[string]int64) {
+	for id, block := range covered {
+		_, inFly := a.inFlyOPs[id]
+		_, received := a.receivedOps[id]
+		if !inFly && !received {
+			continue
+		}
+		delete(a.inFlyOPs, id)
+		delete(a.receivedOps, id)
+		delete(a.rawOps, id)
+		delete(a.ackedAt, id)
+		// the result is in the snapshot state only
+		a.notify(OpStatus{ID: id, State: OpCommitted, Block: block})
+	}
+	a.ackedHashes = slices.DeleteFunc(a.ackedHashes, func(id string) bool {
+		_, ok := covered[id]
+		return ok
+	})
+}
@@ -0,0 +1,228 @@
+package maroon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/akantsevoi/test-environment/pkg/wal"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func testOps(from, to int) []Operation {
+	var res []Operation
+	for i := from; i < to; i++ {
+		res = append(res, Operation{OpType: PrintTimestamp, Value: fmt.Sprint(i), Nonce: uint64(i) + 1})
+	}
+	return res
+}
+
+// applies blocks of 3 operations one after another
+func applyTestBlocks(t *testing.T, a *application, ops []Operation) {
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+	for i := 0; i < len(ops); i += 3 {
+		blockOps := ops[i : i+3]
+		var hashes []string
+		for _, op := range blockOps {
+			hashes = append(hashes, op.Hash())
+		}
+		block, err := newBlock(a.batchCounter, a.prevBlockHash, hashes)
+		require.NoError(t, err)
+		blockHash, err := block.Hash()
+		require.NoError(t, err)
+		a.applyBlock(block, blockHash, blockOps)
+	}
+}
+
+func TestSnapshotCompactsConfirmedOps(t *testing.T) {
+	dir := t.TempDir()
+	w, err := wal.Open(dir, wal.WithSegmentSize(512))
+	require.NoError(t, err)
+
+	app := New(etcdmock.New(), &servMock{}, WithWAL(w), WithSnapshotEvery(2))
+	ops := testOps(0, 12)
+	applyTestBlocks(t, app, ops)
+
+	block, data, ok := app.LatestSnapshot()
+	require.True(t, ok)
+	require.Equal(t, int64(3), block)
+	snap, err := decodeSnapshot(data)
+	require.NoError(t, err)
+	require.Equal(t, 12, snap.Ops)
+	require.Equal(t, app.prevBlockHash, snap.BlockHash)
+
+	// operations since the previous snapshot at block 1 are still there
+	require.Equal(t, ops[6:], app.confirmedOps)
+	require.Empty(t, app.GetTxs([]string{ops[5].Hash()}))
+	require.Len(t, app.GetTxs([]string{ops[6].Hash()}), 1)
+
+	// uncommitted operation goes through the snapshot
+	pending := Operation{OpType: PrintTimestamp, Value: "pending"}
+	hash, message := hashBin(t, pending)
+	require.NoError(t, app.StoreTx(p2p.Transaction{ID: hash, TxData: message}))
+	applyTestBlocks(t, app, testOps(12, 18))
+	require.NoError(t, w.Close())
+
+	w, err = wal.Open(dir, wal.WithSegmentSize(512))
+	require.NoError(t, err)
+	defer w.Close()
+	// log before the snapshot is dropped
+	var first uint64
+	require.NoError(t, w.Replay(func(index uint64, _ []byte) error {
+		if first == 0 {
+			first = index
+		}
+		return nil
+	}))
+	require.Greater(t, first, uint64(1))
+
+	restarted := New(etcdmock.New(), &servMock{}, WithWAL(w), WithSnapshotEvery(2))
+	require.NoError(t, restarted.Recover())
+
+	require.Equal(t, int64(6), restarted.batchCounter)
+	require.Equal(t, app.prevBlockHash, restarted.prevBlockHash)
+	require.Equal(t, app.sm, restarted.sm)
+	require.Equal(t, map[string]Operation{hash: pending}, restarted.receivedOps)
+}
+
+func TestFollowerInstallsSnapshot(t *testing.T) {
+	leader := New(etcdmock.New(), &servMock{}, WithSnapshotEvery(1))
+	ops := testOps(0, 12)
+	applyTestBlocks(t, leader, ops[:9])
+
+	snapBlock, snapData, ok := leader.LatestSnapshot()
+	require.True(t, ok)
+	prevHash := leader.prevBlockHash
+
+	serv := &servMock{
+		snapshot: func(ctx context.Context) (int64, []byte, error) {
+			return snapBlock, snapData, nil
+		},
+		fetch: func(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+			return leader.GetTxs(ids), nil
+		},
+	}
+	applyTestBlocks(t, leader, ops[9:])
+
+	// the snapshot is checked against its block
+	etcd := etcdmock.New()
+	putTestBlocks(t, etcd, testChain(t, ops[:9])[2:])
+
+	etcdWatchCh := make(chan clientv3.WatchResponse)
+	stopCh := make(chan struct{})
+	follower := New(etcd, serv)
+	go follower.Run(make(chan Leadership), make(chan p2p.TransactionDistributed), etcdWatchCh, stopCh)
+	defer close(stopCh)
+
+	// follower has nothing and gets the last block
+	var hashes []string
+	for _, op := range ops[9:] {
+		hashes = append(hashes, op.Hash())
+	}
+	block, err := newBlock(3, prevHash, hashes)
+	require.NoError(t, err)
+	record, err := block.Encode()
+	require.NoError(t, err)
+	etcdWatchCh <- clientv3.WatchResponse{
+		Events: []*clientv3.Event{{
+			Type: clientv3.EventTypePut,
+			Kv:   &mvccpb.KeyValue{Key: []byte(blockKey(3)), Value: record},
+		}},
+	}
+
+	require.Eventually(t, func() bool {
+		follower.opMU.Lock()
+		defer follower.opMU.Unlock()
+		return follower.batchCounter == 4
+	}, time.Second, 10*time.Millisecond)
+
+	follower.opMU.Lock()
+	defer follower.opMU.Unlock()
+	require.Equal(t, leader.prevBlockHash, follower.prevBlockHash)
+	require.Equal(t, leader.sm, follower.sm)
+	require.Equal(t, ops[9:], follower.confirmedOps)
+}
+
+func putTestBlocks(t *testing.T, etcd ETCD, blocks []Block) {
+	for _, block := range blocks {
+		record, err := block.Encode()
+		require.NoError(t, err)
+		_, err = etcd.Put(context.Background(), blockKey(block.Number), string(record))
+		require.NoError(t, err)
+	}
+}
+
+func TestInstalledSnapshotDropsCoveredPendingOps(t *testing.T) {
+	peer := New(etcdmock.New(), &servMock{}, WithSnapshotEvery(1))
+	ops := testOps(0, 9)
+	applyTestBlocks(t, peer, ops)
+	snapBlock, snapData, ok := peer.LatestSnapshot()
+	require.True(t, ok)
+
+	etcd, leadership := leaderETCD(t)
+	putTestBlocks(t, etcd, testChain(t, ops))
+	var distributed []string
+	serv := &servMock{
+		snapshot: func(ctx context.Context) (int64, []byte, error) {
+			return snapBlock, snapData, nil
+		},
+		distr: func(tx p2p.Transaction) { distributed = append(distributed, tx.ID) },
+	}
+	node := New(etcd, serv)
+
+	// acked ops, one of them is in a block already
+	pending := Operation{OpType: PrintTimestamp, Value: "pending", Nonce: 100}
+	for _, op := range []Operation{ops[4], pending} {
+		hash, message := hashBin(t, op)
+		require.NoError(t, node.StoreTx(p2p.Transaction{ID: hash, TxData: message}))
+	}
+	require.NoError(t, node.installSnapshot(context.Background()))
+	require.Equal(t, map[string]Operation{pending.Hash(): pending}, node.receivedOps)
+
+	// the node becomes the leader, the peers know only the pending one
+	serv.pending = func(ctx context.Context) ([]p2p.Transaction, error) {
+		return node.PendingTxs(), nil
+	}
+	node.isLeader, node.leaderRev = true, leadership.Revision
+	node.recoverPending(context.Background(), leadership.Revision)
+	require.Equal(t, []string{pending.Hash()}, distributed)
+}
+
+func TestSnapshotNotMatchingBlockIsRejected(t *testing.T) {
+	peer := New(etcdmock.New(), &servMock{}, WithSnapshotEvery(1))
+	ops := testOps(0, 9)
+	applyTestBlocks(t, peer, ops)
+	snapBlock, snapData, ok := peer.LatestSnapshot()
+	require.True(t, ok)
+	snap, err := decodeSnapshot(snapData)
+	require.NoError(t, err)
+	snap.Root = strings.Repeat("0", 64)
+	forged, err := json.Marshal(snap)
+	require.NoError(t, err)
+
+	etcd := etcdmock.New()
+	putTestBlocks(t, etcd, testChain(t, ops))
+	node := New(etcd, &servMock{
+		snapshot: func(ctx context.Context) (int64, []byte, error) {
+			return snapBlock, forged, nil
+		},
+	})
+	require.ErrorContains(t, node.installSnapshot(context.Background()), "doesn't match block 2")
+	require.Equal(t, int64(0), node.batchCounter)
+
+	// nothing to check it against
+	empty := New(etcdmock.New(), &servMock{
+		snapshot: func(ctx context.Context) (int64, []byte, error) {
+			return snapBlock, snapData, nil
+		},
+	})
+	require.ErrorContains(t, empty.installSnapshot(context.Background()), "not in etcd")
+}
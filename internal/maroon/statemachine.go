@@ -0,0 +1,134 @@
+package maroon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrUnknownOpType = errors.New("unknown operation type")
+
+// deterministic state built from the committed operations in block order
+// every node applies the same operations in the same order and gets the same state
+//
+// errors of Apply are results of the operation, like a failed CAS,
+// they're the same on every node and don't stop the application
+type StateMachine interface {
+	Apply(op Operation) ([]byte, error)
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// routes operations to the state machines by their type
+// it's a state machine itself, snapshot has all the registered machines
+type Registry struct {
+	machines map[string]StateMachine
+	byType   map[OperationType]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		machines: make(map[string]StateMachine),
+		byType:   make(map[OperationType]string),
+	}
+}
+
+// timestamps and key-value
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.MustRegister("timestamp", newTimestampMachine(), PrintTimestamp)
+	r.MustRegister("kv", NewKV(), KVTypes...)
+	return r
+}
+
+// name is the key of the machine in snapshots, so it shouldn't change
+func (r *Registry) Register(name string, sm StateMachine, types ...OperationType) error {
+	if _, ok := r.machines[name]; ok {
+		return fmt.Errorf("state machine %q is already registered", name)
+	}
+	for _, t := range types {
+		if other, ok := r.byType[t]; ok {
+			return fmt.Errorf("operation type %d is already handled by %q", t, other)
+		}
+	}
+
+	r.machines[name] = sm
+	for _, t := range types {
+		r.byType[t] = name
+	}
+	return nil
+}
+
+func (r *Registry) MustRegister(name string, sm StateMachine, types ...OperationType) {
+	if err := r.Register(name, sm, types...); err != nil {
+		panic(err)
+	}
+}
+
+func (r *Registry) Apply(op Operation) ([]byte, error) {
+	name, ok := r.byType[op.OpType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownOpType, op.OpType)
+	}
+	return r.machines[name].Apply(op)
+}
+
+// map keys are sorted by encoding/json, so the snapshot is the same on every node
+func (r *Registry) Snapshot() ([]byte, error) {
+	snaps := make(map[string][]byte, len(r.machines))
+	for name, sm := range r.machines {
+		data, err := sm.Snapshot()
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot %q: %w", name, err)
+		}
+		snaps[name] = data
+	}
+	return json.Marshal(snaps)
+}
+
+func (r *Registry) Restore(data []byte) error {
+	var snaps map[string][]byte
+	if err := json.Unmarshal(data, &snaps); err != nil {
+		return fmt.Errorf("failed to decode registry snapshot: %w", err)
+	}
+	for name, sm := range r.machines {
+		snap, ok := snaps[name]
+		if !ok {
+			// registered after the snapshot was taken
+			continue
+		}
+		if err := sm.Restore(snap); err != nil {
+			return fmt.Errorf("failed to restore %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// the only operation for now just carries a timestamp
+type timestampMachine struct {
+	Applied       int64  `json:"applied"`
+	LastTimestamp string `json:"lastTimestamp"`
+}
+
+func newTimestampMachine() *timestampMachine {
+	return &timestampMachine{}
+}
+
+func (s *timestampMachine) Apply(op Operation) ([]byte, error) {
+	s.Applied++
+	s.LastTimestamp = op.Value
+	return nil, nil
+}
+
+func (s *timestampMachine) Snapshot() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+func (s *timestampMachine) Restore(data []byte) error {
+	var restored timestampMachine
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("failed to restore timestamp state: %w", err)
+	}
+	*s = restored
+	return nil
+}
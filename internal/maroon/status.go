@@ -0,0 +1,65 @@
+package maroon
+
+import "errors"
+
+var (
+	ErrNotLeader = errors.New("node is not the leader")
+	// followers don't keep up, client has to retry later
+	ErrOverloaded = errors.New("node is overloaded")
+)
+
+type OpState int
+
+const (
+	// leader got the operation
+	OpAccepted OpState = iota + 1
+	// enough followers stored the operation
+	OpDistributed
+	// block with the operation is put to etcd
+	OpInBlock
+	// block is applied on the leader
+	OpCommitted
+)
+
+func (s OpState) String() string {
+	switch s {
+	case OpAccepted:
+		return "accepted"
+	case OpDistributed:
+		return "distributed"
+	case OpInBlock:
+		return "in block"
+	case OpCommitted:
+		return "committed"
+	}
+	return "unknown"
+}
+
+type OpStatus struct {
+	// hash of the operation
+	ID    string
+	State OpState
+	// set since OpInBlock
+	Block int64
+	// OpCommitted only, outcome of the state machine
+	Result []byte
+	Err    error
+}
+
+// every state is sent once, so the channel never blocks
+const opStatusBuffer = int(OpCommitted)
+
+// sends the status to everybody who waits for the operation
+// the channels are closed after OpCommitted
+// should be called under opMU
+func (a *application) notify(status OpStatus) {
+	for _, ch := range a.opWatchers[status.ID] {
+		ch <- status
+		if status.State == OpCommitted {
+			close(ch)
+		}
+	}
+	if status.State == OpCommitted {
+		delete(a.opWatchers, status.ID)
+	}
+}
@@ -0,0 +1,125 @@
+package maroon
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// position of a transaction in the key range space, see doc/key-range.md
+type OffsetKey struct {
+	RangeIndex uint64
+	Offset     uint64
+}
+
+// OffsetVector keeps for every key range how far the node got
+// value n means that offsets [0, n) of the range are there
+// absent range is the same as 0
+type OffsetVector map[uint64]uint64
+
+func (v OffsetVector) Copy() OffsetVector {
+	res := make(OffsetVector, len(v))
+	for r, o := range v {
+		res[r] = o
+	}
+	return res
+}
+
+// true if v is ahead of other at least in one range
+func (v OffsetVector) Ahead(other OffsetVector) bool {
+	for r, o := range v {
+		if o > other[r] {
+			return true
+		}
+	}
+	return false
+}
+
+// element-wise max
+func (v OffsetVector) Merge(other OffsetVector) OffsetVector {
+	res := v.Copy()
+	for r, o := range other {
+		if o > res[r] {
+			res[r] = o
+		}
+	}
+	return res
+}
+
+// ranges in ascending order, so every node goes through them the same way
+func (v OffsetVector) ranges() []uint64 {
+	res := make([]uint64, 0, len(v))
+	for r := range v {
+		res = append(res, r)
+	}
+	slices.Sort(res)
+	return res
+}
+
+// element-wise value that at least a majority of clusterSize nodes has reached
+// nodes that didn't report their vector are counted as empty ones
+//
+//	N1 <(1,10), (2,14), (3,7)>
+//	N2 <(1,10), (2,12), (3,6)>
+//	N3 <(1,11), (2,13), (3,7)>
+//	-> <(1,10), (2,13), (3,7)>
+func Majority(vectors []OffsetVector, clusterSize int) OffsetVector {
+	if len(vectors) > clusterSize {
+		clusterSize = len(vectors)
+	}
+
+	ranges := make(map[uint64]bool)
+	for _, v := range vectors {
+		for r := range v {
+			ranges[r] = true
+		}
+	}
+
+	res := make(OffsetVector)
+	for r := range ranges {
+		offsets := make([]uint64, clusterSize)
+		for i, v := range vectors {
+			offsets[i] = v[r]
+		}
+		slices.Sort(offsets)
+		slices.Reverse(offsets)
+
+		// clusterSize/2+1 nodes have at least that offset
+		if o := offsets[clusterSize/2]; o > 0 {
+			res[r] = o
+		}
+	}
+	return res
+}
+
+// keys that are in `to` but not in `from` in the deterministic order:
+// ranges ascending, offsets ascending inside the range
+func orderedKeys(from, to OffsetVector) []OffsetKey {
+	var res []OffsetKey
+	for _, r := range to.ranges() {
+		for o := from[r]; o < to[r]; o++ {
+			res = append(res, OffsetKey{RangeIndex: r, Offset: o})
+		}
+	}
+	return res
+}
+
+// record that the leader puts into etcd under VectorKey
+// every node applies them one by one in Seq order
+// so the transactions are ordered the same way everywhere
+type CommittedRecord struct {
+	Seq    uint64       `json:"seq"`
+	Vector OffsetVector `json:"vector"`
+}
+
+func DecodeCommittedRecord(data []byte) (CommittedRecord, error) {
+	var rec CommittedRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return CommittedRecord{}, fmt.Errorf("failed to decode committed vector: %w", err)
+	}
+	return rec, nil
+}
+
+func (r CommittedRecord) Encode() ([]byte, error) {
+	return json.Marshal(r)
+}
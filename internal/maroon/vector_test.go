@@ -0,0 +1,36 @@
+package maroon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMajorityFromDoc(t *testing.T) {
+	majority := Majority([]OffsetVector{
+		{1: 10, 2: 14, 3: 7},
+		{1: 10, 2: 12, 3: 6},
+		{1: 11, 2: 13, 3: 7},
+	}, 3)
+
+	require.Equal(t, OffsetVector{1: 10, 2: 13, 3: 7}, majority)
+}
+
+func TestMajorityCountsSilentNodes(t *testing.T) {
+	// 2 of 5 nodes reported - not a majority for anything
+	require.Empty(t, Majority([]OffsetVector{{1: 10}, {1: 10}}, 5))
+
+	// 3 of 5 nodes have at least 4
+	require.Equal(t, OffsetVector{1: 4}, Majority([]OffsetVector{{1: 10}, {1: 4}, {1: 7}}, 5))
+}
+
+func TestOrderedKeys(t *testing.T) {
+	keys := orderedKeys(OffsetVector{1: 2, 5: 1}, OffsetVector{5: 3, 1: 3, 2: 1})
+
+	require.Equal(t, []OffsetKey{
+		{RangeIndex: 1, Offset: 2},
+		{RangeIndex: 2, Offset: 0},
+		{RangeIndex: 5, Offset: 1},
+		{RangeIndex: 5, Offset: 2},
+	}, keys)
+}
@@ -0,0 +1,152 @@
+package maroon
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+type walKind string
+
+const (
+	// operation that the node got as a leader or as a follower
+	walOp walKind = "op"
+	// leader: operation is distributed to the followers
+	walAck walKind = "ack"
+	// block is applied, operations are there as well
+	// because followers can fetch them from the peers without logging
+	walBlock walKind = "block"
+	// replaces everything before it, pending operations are there as well
+	walSnapshot walKind = "snapshot"
+)
+
+type walRecord struct {
+	Kind   walKind     `json:"kind"`
+	ID     string      `json:"id,omitempty"`
+	Op     *Operation  `json:"op,omitempty"`
+	Leader bool        `json:"leader,omitempty"`
+	Block  *Block      `json:"block,omitempty"`
+	Ops    []Operation `json:"ops,omitempty"`
+
+	Snapshot *Snapshot            `json:"snapshot,omitempty"`
+	InFly    map[string]Operation `json:"inFly,omitempty"`
+	Received map[string]Operation `json:"received,omitempty"`
+	Acked    []string             `json:"acked,omitempty"`
+
+	// envelopes of the operations in the record that can't be encoded back, see keepRaw
+	Raw map[string][]byte `json:"raw,omitempty"`
+}
+
+// should be called under opMU
+func (a *application) logRecord(rec walRecord) error {
+	if a.wal == nil {
+		return nil
+	}
+	_, err := a.appendRecord(rec)
+	return err
+}
+
+// returns index of the record in the wal
+// should be called under opMU
+func (a *application) appendRecord(rec walRecord) (uint64, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return 0, err
+	}
+	index, err := a.wal.Append(data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write wal: %w", err)
+	}
+	return index, nil
+}
+
+// rebuilds the state from the wal
+// has to be called before Run
+// TODO: blocks that were put to etcd while the node was down come only from the watch
+func (a *application) Recover() error {
+	if a.wal == nil {
+		return nil
+	}
+
+	a.opMU.Lock()
+	defer a.opMU.Unlock()
+
+	var records int
+	err := a.wal.Replay(func(index uint64, data []byte) error {
+		records++
+		var rec walRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("bad wal record %d: %w", index, err)
+		}
+
+		switch rec.Kind {
+		case walOp:
+			if rec.Op == nil {
+				return fmt.Errorf("wal record %d: op is missing", index)
+			}
+			if _, ok := a.confirmedIdx[rec.ID]; ok {
+				return nil
+			}
+			if rec.Leader {
+				a.inFlyOPs[rec.ID] = *rec.Op
+			} else {
+				a.receivedOps[rec.ID] = *rec.Op
+			}
+			maps.Copy(a.rawOps, rec.Raw)
+		case walAck:
+			a.ackedHashes = append(a.ackedHashes, rec.ID)
+		case walBlock:
+			if rec.Block == nil || len(rec.Ops) != len(rec.Block.TxIDs) {
+				return fmt.Errorf("wal record %d: broken block", index)
+			}
+			blockHash, err := rec.Block.Hash()
+			if err != nil {
+				return err
+			}
+			maps.Copy(a.rawOps, rec.Raw)
+			a.appendBlockOps(*rec.Block, blockHash, rec.Ops)
+		case walSnapshot:
+			if rec.Snapshot == nil {
+				return fmt.Errorf("wal record %d: snapshot is missing", index)
+			}
+			snapData, err := json.Marshal(rec.Snapshot)
+			if err != nil {
+				return err
+			}
+			if err := a.restoreSnapshot(*rec.Snapshot, snapData); err != nil {
+				return fmt.Errorf("wal record %d: %w", index, err)
+			}
+			a.inFlyOPs = orEmpty(rec.InFly)
+			a.receivedOps = orEmpty(rec.Received)
+			a.ackedHashes = rec.Acked
+			a.rawOps = make(map[string][]byte)
+			maps.Copy(a.rawOps, rec.Raw)
+		default:
+			return fmt.Errorf("wal record %d: unknown kind %q", index, rec.Kind)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// acks of the operations that are in blocks already are not needed anymore
+	a.ackedHashes = slices.DeleteFunc(a.ackedHashes, func(id string) bool {
+		_, ok := a.inFlyOPs[id]
+		return !ok
+	})
+
+	logger.Infof(logger.Application, "recovered from wal: %d records, %d confirmed ops, next block %d",
+		records, len(a.confirmedOps), a.batchCounter)
+	return nil
+}
+
+func orEmpty(ops map[string]Operation) map[string]Operation {
+	if ops == nil {
+		return make(map[string]Operation)
+	}
+	return ops
+}
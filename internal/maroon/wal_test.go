@@ -0,0 +1,106 @@
+package maroon
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/p2p"
+	"github.com/akantsevoi/test-environment/pkg/wal"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func TestRecoverFromWAL(t *testing.T) {
+	dir := t.TempDir()
+	w, err := wal.Open(dir)
+	require.NoError(t, err)
+
+	etcd, leadership := leaderETCD(t)
+	serv := &servMock{distr: func(tx p2p.Transaction) {}}
+	opDistributedCh := make(chan p2p.TransactionDistributed)
+	isLeaderCh := make(chan Leadership)
+	stopCh := make(chan struct{})
+
+	// no linger, so the last operation stays acked and never goes into a block
+	app := New(etcd, serv, WithWAL(w), WithSealPolicy(SealPolicy{MaxOps: 3}))
+	require.NoError(t, app.Recover())
+	doneCh := make(chan struct{})
+	go func() {
+		app.Run(isLeaderCh, opDistributedCh, make(clientv3.WatchChan), stopCh)
+		close(doneCh)
+	}()
+	isLeaderCh <- leadership
+
+	ops := []Operation{
+		{OpType: PrintTimestamp, Value: "1", Nonce: 1},
+		{OpType: PrintTimestamp, Value: "2", Nonce: 2},
+		{OpType: PrintTimestamp, Value: "3", Nonce: 3},
+		{OpType: PrintTimestamp, Value: "4", Nonce: 4},
+	}
+	var chs []<-chan OpStatus
+	for _, op := range ops {
+		require.Eventually(t, func() bool {
+			ch, err := app.AddOp(op)
+			if err != nil {
+				return false
+			}
+			chs = append(chs, ch)
+			return true
+		}, time.Second, 10*time.Millisecond)
+	}
+	// the last one is acked but not in a block yet
+	for _, op := range ops {
+		opDistributedCh <- p2p.TransactionDistributed{ID: op.Hash()}
+	}
+	for range chs[0] {
+	}
+	// the ack has to be in the wal before it's reopened
+	require.Eventually(t, func() bool {
+		return walHasAck(t, w, ops[3].Hash())
+	}, time.Second, 10*time.Millisecond)
+	close(stopCh)
+	<-doneCh
+	require.NoError(t, w.Close())
+
+	// follower got one more operation from the leader
+	w, err = wal.Open(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	follower := New(etcd, serv, WithWAL(w))
+	require.NoError(t, follower.Recover())
+
+	follower.opMU.Lock()
+	require.Equal(t, ops[:3], follower.confirmedOps)
+	require.Equal(t, int64(1), follower.batchCounter)
+	require.NotEmpty(t, follower.prevBlockHash)
+	require.Equal(t, map[string]Operation{ops[3].Hash(): ops[3]}, follower.inFlyOPs)
+	require.Equal(t, []string{ops[3].Hash()}, follower.ackedHashes)
+	follower.opMU.Unlock()
+
+	op5 := Operation{OpType: PrintTimestamp, Value: "5"}
+	hash, message := hashBin(t, op5)
+	require.NoError(t, follower.StoreTx(p2p.Transaction{ID: hash, TxData: message}))
+	require.NoError(t, w.Close())
+
+	w, err = wal.Open(dir)
+	require.NoError(t, err)
+	defer w.Close()
+	restarted := New(etcd, serv, WithWAL(w))
+	require.NoError(t, restarted.Recover())
+	require.Equal(t, map[string]Operation{hash: op5}, restarted.receivedOps)
+	require.Len(t, restarted.confirmedOps, 3)
+}
+
+func walHasAck(t *testing.T, w *wal.WAL, id string) bool {
+	found := false
+	require.NoError(t, w.Replay(func(index uint64, data []byte) error {
+		var rec walRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return err
+		}
+		found = found || (rec.Kind == walAck && rec.ID == id)
+		return nil
+	}))
+	return found
+}
@@ -0,0 +1,81 @@
+package p2p
+
+import (
+	"context"
+	"time"
+
+	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+// gossip is best effort, the vectors are republished periodically anyway
+const gossipTimeout = 2 * time.Second
+
+func (s *serv) SetOffsetStore(store OffsetStore) {
+	s.offsetStore = store
+}
+
+func (s *serv) GossipOffsetTxs(txs []OffsetTx) {
+	req := &maroonv1.GossipTxsRequest{}
+	for _, tx := range txs {
+		req.Txs = append(req.Txs, &maroonv1.OffsetTx{
+			RangeIndex: tx.RangeIndex,
+			Offset:     tx.Offset,
+			Payload:    tx.Payload,
+			Noop:       tx.NoOp,
+		})
+	}
+
+	s.broadcast(func(ctx context.Context, client maroonv1.P2PServiceClient) error {
+		_, err := client.GossipTxs(ctx, req)
+		return err
+	})
+}
+
+func (s *serv) BroadcastVector(vector map[uint64]uint64) {
+	req := &maroonv1.PublishVectorRequest{
+		NodeId:  s.nodeID,
+		Offsets: vectorToProto(vector),
+	}
+
+	s.broadcast(func(ctx context.Context, client maroonv1.P2PServiceClient) error {
+		_, err := client.PublishVector(ctx, req)
+		return err
+	})
+}
+
+// calls every known peer in its own goroutine
+func (s *serv) broadcast(call func(ctx context.Context, client maroonv1.P2PServiceClient) error) {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	for host, hostI := range s.clients {
+		client := hostI.client
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), gossipTimeout)
+			defer cancel()
+			if err := call(ctx, client); err != nil {
+				logger.Warningf(logger.Network, "gossip to %v failed: %v", host, err)
+			}
+		}()
+	}
+}
+
+func vectorToProto(vector map[uint64]uint64) []*maroonv1.RangeOffset {
+	res := make([]*maroonv1.RangeOffset, 0, len(vector))
+	for rangeIndex, offset := range vector {
+		res = append(res, &maroonv1.RangeOffset{
+			RangeIndex: rangeIndex,
+			Offset:     offset,
+		})
+	}
+	return res
+}
+
+func vectorFromProto(offsets []*maroonv1.RangeOffset) map[uint64]uint64 {
+	res := make(map[uint64]uint64, len(offsets))
+	for _, o := range offsets {
+		res[o.RangeIndex] = o.Offset
+	}
+	return res
+}
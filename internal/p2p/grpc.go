@@ -2,18 +2,94 @@ package p2p
 
 import (
 	"context"
-	"time"
 
 	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
 	"github.com/akantsevoi/test-environment/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *serv) AddTx(_ context.Context, req *maroonv1.AddTxRequest) (*maroonv1.AddTxResponse, error) {
-	logger.Infof(logger.Network, "got message addtx: %v", req.String())
+	logger.Infof(logger.Network, "got message addtx: %v", req.Id)
 
-	// TODO: imitate that we store transaction somewhere
-	// implement passing this tx to application layer
-	time.Sleep(30 * time.Millisecond)
+	if s.store == nil {
+		return nil, status.Error(codes.Unavailable, "node is not ready to store transactions")
+	}
+
+	err := s.store.StoreTx(Transaction{
+		ID:     req.Id,
+		TxData: req.Payload,
+	})
+	if err != nil {
+		logger.Errorf(logger.Network, "failed to store tx %v: %v", req.Id, err)
+		return &maroonv1.AddTxResponse{Acced: false}, nil
+	}
 
 	return &maroonv1.AddTxResponse{Acced: true}, nil
 }
+
+func (s *serv) GetTxs(_ context.Context, req *maroonv1.GetTxsRequest) (*maroonv1.GetTxsResponse, error) {
+	resp := &maroonv1.GetTxsResponse{}
+	if len(req.Ids) == 0 {
+		// rtt probe, see replica.probe
+		return resp, nil
+	}
+	logger.Infof(logger.Network, "got request gettxs: %d ids", len(req.Ids))
+
+	if s.store == nil {
+		return resp, nil
+	}
+
+	for _, tx := range s.store.GetTxs(req.Ids) {
+		resp.Txs = append(resp.Txs, &maroonv1.Tx{
+			Id:      tx.ID,
+			Payload: tx.TxData,
+		})
+	}
+	return resp, nil
+}
+
+func (s *serv) GetPendingTxs(_ context.Context, _ *maroonv1.GetPendingTxsRequest) (*maroonv1.GetPendingTxsResponse, error) {
+	if s.store == nil {
+		return nil, status.Error(codes.Unavailable, "node is not ready to serve transactions")
+	}
+
+	resp := &maroonv1.GetPendingTxsResponse{}
+	for _, tx := range s.store.PendingTxs() {
+		resp.Txs = append(resp.Txs, &maroonv1.Tx{
+			Id:      tx.ID,
+			Payload: tx.TxData,
+		})
+	}
+	logger.Infof(logger.Network, "got request getpendingtxs: %d pending", len(resp.Txs))
+	return resp, nil
+}
+
+func (s *serv) GossipTxs(_ context.Context, req *maroonv1.GossipTxsRequest) (*maroonv1.GossipTxsResponse, error) {
+	if s.offsetStore == nil {
+		return nil, status.Error(codes.Unavailable, "node is not ready to store transactions")
+	}
+
+	txs := make([]OffsetTx, 0, len(req.Txs))
+	for _, tx := range req.Txs {
+		txs = append(txs, OffsetTx{
+			RangeIndex: tx.RangeIndex,
+			Offset:     tx.Offset,
+			Payload:    tx.Payload,
+			NoOp:       tx.Noop,
+		})
+	}
+	if err := s.offsetStore.StoreOffsetTxs(txs); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to store txs: %v", err)
+	}
+	return &maroonv1.GossipTxsResponse{}, nil
+}
+
+func (s *serv) PublishVector(_ context.Context, req *maroonv1.PublishVectorRequest) (*maroonv1.PublishVectorResponse, error) {
+	if s.offsetStore == nil {
+		return nil, status.Error(codes.Unavailable, "node is not ready to observe vectors")
+	}
+
+	s.offsetStore.ObserveVector(req.NodeId, vectorFromProto(req.Offsets))
+	return &maroonv1.PublishVectorResponse{}, nil
+}
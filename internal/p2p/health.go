@@ -0,0 +1,132 @@
+package p2p
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	"google.golang.org/grpc/connectivity"
+)
+
+const (
+	// broken replication streams in a row after which the peer is unhealthy
+	maxPeerFailures = 3
+	// weight of a new rtt sample
+	rttAlpha = 0.2
+	// events are dropped if nobody reads them
+	peerEventsBuffer = 64
+)
+
+type PeerHealth struct {
+	Peer
+	ConnState connectivity.State
+	// zero if the peer never acked anything
+	LastAck time.Time
+	// moving average of the time between sending a batch and its ack
+	// zero until the first ack
+	RTT time.Duration
+	// broken replication streams in a row
+	Failures int
+	Healthy  bool
+}
+
+// sent when the peer becomes healthy or unhealthy, or its connection state changes
+// removed peers come with the Shutdown state
+type PeerEvent struct {
+	PeerHealth
+}
+
+// should be called under replica.mu
+func (r *replica) healthLocked() PeerHealth {
+	h := PeerHealth{
+		Peer:      Peer{Addr: r.host, Region: r.region},
+		ConnState: r.connState,
+		LastAck:   r.lastAck,
+		RTT:       r.rtt,
+		Failures:  r.failures,
+	}
+	h.Healthy = h.ConnState != connectivity.TransientFailure &&
+		h.ConnState != connectivity.Shutdown &&
+		h.Failures < maxPeerFailures
+	return h
+}
+
+func (r *replica) health() PeerHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.healthLocked()
+}
+
+// changes the health under mu and sends an event if it matters
+func (r *replica) updateHealth(update func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	before := r.healthLocked()
+	update()
+	after := r.healthLocked()
+	if before.Healthy == after.Healthy && before.ConnState == after.ConnState {
+		return
+	}
+	select {
+	case r.eventsCh <- PeerEvent{PeerHealth: after}:
+	default:
+		logger.Warningf(logger.Network, "peer events are not consumed, event of %v is dropped", r.host)
+	}
+}
+
+// should be called under mu
+func (r *replica) sampleRTT(sample time.Duration) {
+	if r.rtt == 0 {
+		r.rtt = sample
+		return
+	}
+	r.rtt = time.Duration(rttAlpha*float64(sample) + (1-rttAlpha)*float64(r.rtt))
+}
+
+func (s *serv) PeerEvents() <-chan PeerEvent {
+	return s.peerEventsCh
+}
+
+func (s *serv) Peers() []PeerHealth {
+	s.clientsMu.RLock()
+	res := make([]PeerHealth, 0, len(s.clients))
+	for _, hostI := range s.clients {
+		res = append(res, hostI.replica.health())
+	}
+	s.clientsMu.RUnlock()
+
+	slices.SortFunc(res, func(a, b PeerHealth) int { return strings.Compare(a.Addr, b.Addr) })
+	return res
+}
+
+type peerClient struct {
+	host   string
+	client maroonv1.P2PServiceClient
+}
+
+// healthy peers go first, random order otherwise to spread the load
+func (s *serv) peersByHealth() []peerClient {
+	s.clientsMu.RLock()
+	peers := make([]peerClient, 0, len(s.clients))
+	healthy := make(map[string]bool, len(s.clients))
+	for host, hostI := range s.clients {
+		peers = append(peers, peerClient{host: host, client: hostI.client})
+		healthy[host] = hostI.replica.health().Healthy
+	}
+	s.clientsMu.RUnlock()
+
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	slices.SortStableFunc(peers, func(a, b peerClient) int {
+		switch {
+		case healthy[a.host] == healthy[b.host]:
+			return 0
+		case healthy[a.host]:
+			return -1
+		}
+		return 1
+	})
+	return peers
+}
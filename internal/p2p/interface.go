@@ -1,21 +1,125 @@
 package p2p
 
+import (
+	"context"
+	"errors"
+)
+
 type Transport interface {
 	Start()
 	Stop()
 
-	// nonblocking
-	// will distribute it to some amount of hosts according to the settings I'll introduce later
-	// it's an async channel
-	// confirmation
-	DistributeTx(m Transaction)
+	// puts the tx into the queues of the peers chosen by ReplicaSelector, see WithReplicaSelector
+	// TransactionDistributed comes once the quorum acked it
+	// see WithQueue for what happens when the queues are full
+	// returns ErrQueueFull if the quorum can't be reached because of the full queues
+	// or the peers that are down, see WithHintedHandoff
+	DistributeTx(m Transaction) error
 
 	// blocking
-	UpdateHosts([]string)
+	UpdateHosts([]Peer)
+
+	// health of every peer, sorted by address
+	Peers() []PeerHealth
+
+	// health changes of the peers, see PeerEvent
+	// events are dropped if the channel isn't read
+	PeerEvents() <-chan PeerEvent
+
+	// blocking
+	// asks peers one by one until all the ids are found
+	// returns everything it managed to collect and an error if some ids are still missing
+	FetchTxs(ctx context.Context, ids []string) ([]Transaction, error)
+
+	// blocking
+	// asks all the peers for the transactions they acked but that are not in any block yet
+	// returns the union of them, error only if no peer answered
+	FetchPendingTxs(ctx context.Context) ([]Transaction, error)
+
+	// blocking
+	// asks all the peers and returns the snapshot with the highest block
+	FetchSnapshot(ctx context.Context) (int64, []byte, error)
+
+	// nonblocking
+	// offset vector protocol: sends transactions received from the gateway to all the peers
+	GossipOffsetTxs(txs []OffsetTx)
+
+	// nonblocking
+	// offset vector protocol: sends the node's uncommitted local vector to all the peers
+	BroadcastVector(vector map[uint64]uint64)
+
+	// receives gossip of the offset vector protocol
+	// should be set before Start
+	SetOffsetStore(store OffsetStore)
+
+	// store receives transactions distributed by the leader
+	// and is used to serve transactions requested by other nodes
+	// should be set before Start
+	SetTxStore(store TxStore)
+
+	// serves snapshots to the peers
+	// should be set before Start
+	SetSnapshotSource(source SnapshotSource)
+}
+
+// implemented by the application layer
+type TxStore interface {
+	// called for every received transaction
+	// the sender gets an ack only if it returns without an error
+	// so it should return only after the transaction is stored
+	// errors that won't go away on retry should wrap ErrBadTx
+	StoreTx(tx Transaction) error
+
+	// returns locally known transactions, unknown ids are skipped
+	GetTxs(ids []string) []Transaction
+
+	// transactions that were acked but are not in any block yet
+	PendingTxs() []Transaction
+}
+
+// implemented by the application layer
+type OffsetStore interface {
+	// transactions gossiped by other nodes
+	StoreOffsetTxs(txs []OffsetTx) error
+
+	// uncommitted local vector published by another node
+	ObserveVector(nodeID string, vector map[uint64]uint64)
+}
+
+// implemented by the application layer
+type SnapshotSource interface {
+	// last block in the snapshot and the encoded snapshot
+	LatestSnapshot() (int64, []byte, bool)
+}
+
+// what happens with a new tx when the queue of the peer is full
+type OverflowPolicy int
+
+const (
+	// DistributeTx waits until the peer acks something
+	// a single slow peer slows down everybody
+	OverflowBlock OverflowPolicy = iota
+	// the oldest tx in the queue is dropped, the peer may never get it
+	OverflowDropOldest
+	// the peer doesn't get the tx
+	OverflowReject
+)
+
+var ErrQueueFull = errors.New("peer queues are full")
+
+// the transaction can't be stored by any node, for example it can't be decoded
+// the leader skips it instead of sending it again
+var ErrBadTx = errors.New("bad transaction")
+
+type Peer struct {
+	// hostname:port
+	Addr string
+	// empty region is a region too
+	Region string
 }
 
 // this message comes from the server when the transaction is confirmed by other nodes
-// how many and which nodes TBD
+// how many and which nodes is decided by QuorumPolicy
 type TransactionDistributed struct {
 	// ID of a transaction
 	ID string
@@ -29,3 +133,13 @@ type Transaction struct {
 	// TODO: I'll do smth smarter later
 	TxData []byte
 }
+
+// transaction of the offset vector protocol
+// (RangeIndex, Offset) is assigned by the gateway and is globally unique
+type OffsetTx struct {
+	RangeIndex uint64
+	Offset     uint64
+	Payload    []byte
+	// fills an offset the gateway never used, it's skipped when applied
+	NoOp bool
+}
@@ -10,6 +10,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	p2p "github.com/akantsevoi/test-environment/internal/p2p"
@@ -40,10 +41,24 @@ func (m *MockTransport) EXPECT() *MockTransportMockRecorder {
 	return m.recorder
 }
 
+// BroadcastVector mocks base method.
+func (m *MockTransport) BroadcastVector(vector map[uint64]uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BroadcastVector", vector)
+}
+
+// BroadcastVector indicates an expected call of BroadcastVector.
+func (mr *MockTransportMockRecorder) BroadcastVector(vector any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BroadcastVector", reflect.TypeOf((*MockTransport)(nil).BroadcastVector), vector)
+}
+
 // DistributeTx mocks base method.
-func (m_2 *MockTransport) DistributeTx(m p2p.Transaction) {
+func (m_2 *MockTransport) DistributeTx(m p2p.Transaction) error {
 	m_2.ctrl.T.Helper()
-	m_2.ctrl.Call(m_2, "DistributeTx", m)
+	ret := m_2.ctrl.Call(m_2, "DistributeTx", m)
+	ret0, _ := ret[0].(error)
+	return ret0
 }
 
 // DistributeTx indicates an expected call of DistributeTx.
@@ -52,6 +67,128 @@ func (mr *MockTransportMockRecorder) DistributeTx(m any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DistributeTx", reflect.TypeOf((*MockTransport)(nil).DistributeTx), m)
 }
 
+// FetchPendingTxs mocks base method.
+func (m *MockTransport) FetchPendingTxs(ctx context.Context) ([]p2p.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchPendingTxs", ctx)
+	ret0, _ := ret[0].([]p2p.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchPendingTxs indicates an expected call of FetchPendingTxs.
+func (mr *MockTransportMockRecorder) FetchPendingTxs(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchPendingTxs", reflect.TypeOf((*MockTransport)(nil).FetchPendingTxs), ctx)
+}
+
+// FetchSnapshot mocks base method.
+func (m *MockTransport) FetchSnapshot(ctx context.Context) (int64, []byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchSnapshot", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FetchSnapshot indicates an expected call of FetchSnapshot.
+func (mr *MockTransportMockRecorder) FetchSnapshot(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSnapshot", reflect.TypeOf((*MockTransport)(nil).FetchSnapshot), ctx)
+}
+
+// FetchTxs mocks base method.
+func (m *MockTransport) FetchTxs(ctx context.Context, ids []string) ([]p2p.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchTxs", ctx, ids)
+	ret0, _ := ret[0].([]p2p.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchTxs indicates an expected call of FetchTxs.
+func (mr *MockTransportMockRecorder) FetchTxs(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchTxs", reflect.TypeOf((*MockTransport)(nil).FetchTxs), ctx, ids)
+}
+
+// GossipOffsetTxs mocks base method.
+func (m *MockTransport) GossipOffsetTxs(txs []p2p.OffsetTx) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GossipOffsetTxs", txs)
+}
+
+// GossipOffsetTxs indicates an expected call of GossipOffsetTxs.
+func (mr *MockTransportMockRecorder) GossipOffsetTxs(txs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GossipOffsetTxs", reflect.TypeOf((*MockTransport)(nil).GossipOffsetTxs), txs)
+}
+
+// PeerEvents mocks base method.
+func (m *MockTransport) PeerEvents() <-chan p2p.PeerEvent {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PeerEvents")
+	ret0, _ := ret[0].(<-chan p2p.PeerEvent)
+	return ret0
+}
+
+// PeerEvents indicates an expected call of PeerEvents.
+func (mr *MockTransportMockRecorder) PeerEvents() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PeerEvents", reflect.TypeOf((*MockTransport)(nil).PeerEvents))
+}
+
+// Peers mocks base method.
+func (m *MockTransport) Peers() []p2p.PeerHealth {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Peers")
+	ret0, _ := ret[0].([]p2p.PeerHealth)
+	return ret0
+}
+
+// Peers indicates an expected call of Peers.
+func (mr *MockTransportMockRecorder) Peers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Peers", reflect.TypeOf((*MockTransport)(nil).Peers))
+}
+
+// SetOffsetStore mocks base method.
+func (m *MockTransport) SetOffsetStore(store p2p.OffsetStore) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetOffsetStore", store)
+}
+
+// SetOffsetStore indicates an expected call of SetOffsetStore.
+func (mr *MockTransportMockRecorder) SetOffsetStore(store any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetOffsetStore", reflect.TypeOf((*MockTransport)(nil).SetOffsetStore), store)
+}
+
+// SetSnapshotSource mocks base method.
+func (m *MockTransport) SetSnapshotSource(source p2p.SnapshotSource) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSnapshotSource", source)
+}
+
+// SetSnapshotSource indicates an expected call of SetSnapshotSource.
+func (mr *MockTransportMockRecorder) SetSnapshotSource(source any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSnapshotSource", reflect.TypeOf((*MockTransport)(nil).SetSnapshotSource), source)
+}
+
+// SetTxStore mocks base method.
+func (m *MockTransport) SetTxStore(store p2p.TxStore) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetTxStore", store)
+}
+
+// SetTxStore indicates an expected call of SetTxStore.
+func (mr *MockTransportMockRecorder) SetTxStore(store any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTxStore", reflect.TypeOf((*MockTransport)(nil).SetTxStore), store)
+}
+
 // Start mocks base method.
 func (m *MockTransport) Start() {
 	m.ctrl.T.Helper()
@@ -77,7 +214,7 @@ func (mr *MockTransportMockRecorder) Stop() *gomock.Call {
 }
 
 // UpdateHosts mocks base method.
-func (m *MockTransport) UpdateHosts(arg0 []string) {
+func (m *MockTransport) UpdateHosts(arg0 []p2p.Peer) {
 	m.ctrl.T.Helper()
 	m.ctrl.Call(m, "UpdateHosts", arg0)
 }
@@ -87,3 +224,159 @@ func (mr *MockTransportMockRecorder) UpdateHosts(arg0 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateHosts", reflect.TypeOf((*MockTransport)(nil).UpdateHosts), arg0)
 }
+
+// MockTxStore is a mock of TxStore interface.
+type MockTxStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTxStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockTxStoreMockRecorder is the mock recorder for MockTxStore.
+type MockTxStoreMockRecorder struct {
+	mock *MockTxStore
+}
+
+// NewMockTxStore creates a new mock instance.
+func NewMockTxStore(ctrl *gomock.Controller) *MockTxStore {
+	mock := &MockTxStore{ctrl: ctrl}
+	mock.recorder = &MockTxStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTxStore) EXPECT() *MockTxStoreMockRecorder {
+	return m.recorder
+}
+
+// GetTxs mocks base method.
+func (m *MockTxStore) GetTxs(ids []string) []p2p.Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTxs", ids)
+	ret0, _ := ret[0].([]p2p.Transaction)
+	return ret0
+}
+
+// GetTxs indicates an expected call of GetTxs.
+func (mr *MockTxStoreMockRecorder) GetTxs(ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTxs", reflect.TypeOf((*MockTxStore)(nil).GetTxs), ids)
+}
+
+// PendingTxs mocks base method.
+func (m *MockTxStore) PendingTxs() []p2p.Transaction {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingTxs")
+	ret0, _ := ret[0].([]p2p.Transaction)
+	return ret0
+}
+
+// PendingTxs indicates an expected call of PendingTxs.
+func (mr *MockTxStoreMockRecorder) PendingTxs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingTxs", reflect.TypeOf((*MockTxStore)(nil).PendingTxs))
+}
+
+// StoreTx mocks base method.
+func (m *MockTxStore) StoreTx(tx p2p.Transaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreTx", tx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreTx indicates an expected call of StoreTx.
+func (mr *MockTxStoreMockRecorder) StoreTx(tx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreTx", reflect.TypeOf((*MockTxStore)(nil).StoreTx), tx)
+}
+
+// MockOffsetStore is a mock of OffsetStore interface.
+type MockOffsetStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOffsetStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockOffsetStoreMockRecorder is the mock recorder for MockOffsetStore.
+type MockOffsetStoreMockRecorder struct {
+	mock *MockOffsetStore
+}
+
+// NewMockOffsetStore creates a new mock instance.
+func NewMockOffsetStore(ctrl *gomock.Controller) *MockOffsetStore {
+	mock := &MockOffsetStore{ctrl: ctrl}
+	mock.recorder = &MockOffsetStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOffsetStore) EXPECT() *MockOffsetStoreMockRecorder {
+	return m.recorder
+}
+
+// ObserveVector mocks base method.
+func (m *MockOffsetStore) ObserveVector(nodeID string, vector map[uint64]uint64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ObserveVector", nodeID, vector)
+}
+
+// ObserveVector indicates an expected call of ObserveVector.
+func (mr *MockOffsetStoreMockRecorder) ObserveVector(nodeID, vector any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ObserveVector", reflect.TypeOf((*MockOffsetStore)(nil).ObserveVector), nodeID, vector)
+}
+
+// StoreOffsetTxs mocks base method.
+func (m *MockOffsetStore) StoreOffsetTxs(txs []p2p.OffsetTx) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StoreOffsetTxs", txs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StoreOffsetTxs indicates an expected call of StoreOffsetTxs.
+func (mr *MockOffsetStoreMockRecorder) StoreOffsetTxs(txs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StoreOffsetTxs", reflect.TypeOf((*MockOffsetStore)(nil).StoreOffsetTxs), txs)
+}
+
+// MockSnapshotSource is a mock of SnapshotSource interface.
+type MockSnapshotSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockSnapshotSourceMockRecorder
+	isgomock struct{}
+}
+
+// MockSnapshotSourceMockRecorder is the mock recorder for MockSnapshotSource.
+type MockSnapshotSourceMockRecorder struct {
+	mock *MockSnapshotSource
+}
+
+// NewMockSnapshotSource creates a new mock instance.
+func NewMockSnapshotSource(ctrl *gomock.Controller) *MockSnapshotSource {
+	mock := &MockSnapshotSource{ctrl: ctrl}
+	mock.recorder = &MockSnapshotSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSnapshotSource) EXPECT() *MockSnapshotSourceMockRecorder {
+	return m.recorder
+}
+
+// LatestSnapshot mocks base method.
+func (m *MockSnapshotSource) LatestSnapshot() (int64, []byte, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LatestSnapshot")
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].([]byte)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// LatestSnapshot indicates an expected call of LatestSnapshot.
+func (mr *MockSnapshotSourceMockRecorder) LatestSnapshot() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LatestSnapshot", reflect.TypeOf((*MockSnapshotSource)(nil).LatestSnapshot))
+}
@@ -0,0 +1,64 @@
+package p2p
+
+import "time"
+
+type Option func(*serv)
+
+// region of the node itself, by default it's an empty region
+func WithRegion(region string) Option {
+	return func(s *serv) {
+		s.region = region
+	}
+}
+
+// by default a transaction is distributed once any 2 peers acked it
+func WithQuorum(policy QuorumPolicy) Option {
+	return func(s *serv) {
+		s.quorum = policy
+	}
+}
+
+// every peer has its own queue of txs that are not acked yet
+// by default it's DefaultQueueSize txs and OverflowReject
+func WithQueue(size int, overflow OverflowPolicy) Option {
+	return func(s *serv) {
+		s.replicaCfg.queueSize = size
+		s.replicaCfg.overflow = overflow
+	}
+}
+
+// which peers get a transaction, by default SendToAll
+func WithReplicaSelector(selector ReplicaSelector) Option {
+	return func(s *serv) {
+		s.selector = selector
+	}
+}
+
+// if the quorum isn't reached after the delay, one more spare peer of ReplicaSelector gets the transaction
+// and so on until the quorum or the end of the spare peers
+// by default there are no speculative sends
+func WithSpeculativeSend(after time.Duration) Option {
+	return func(s *serv) {
+		s.speculateAfter = after
+	}
+}
+
+// mTLS for both the server and the connections to the peers
+// a peer is accepted only if its certificate matches one of the hosts from UpdateHosts
+// by default there is no tls
+func WithTLS(files TLSFiles) Option {
+	return func(s *serv) {
+		s.tls = newCertReloader(files)
+	}
+}
+
+// peer that is down for longer than after gets txs as hints, up to max of them
+// they are replayed once the peer is back, but don't count for the quorum or the queue size
+// and aren't dropped by OverflowDropOldest
+// by default it's 10s and 65536 hints, 0 after - no hints
+func WithHintedHandoff(after time.Duration, max int) Option {
+	return func(s *serv) {
+		s.replicaCfg.hintAfter = after
+		s.replicaCfg.maxHints = max
+	}
+}
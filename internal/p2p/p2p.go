@@ -2,13 +2,19 @@ package p2p
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
 	"github.com/akantsevoi/test-environment/pkg/logger"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -16,47 +22,229 @@ type serv struct {
 	maroonv1.UnimplementedP2PServiceServer
 
 	grpc *grpc.Server
+	// closed when the node stops
+	stoppingCh chan struct{}
 
 	// port where to spin a service
 	port string
+	// id of the node for other peers
+	nodeID string
 
-	toDistributeQueueCh chan Transaction
-	distributedTxCh     chan TransactionDistributed
+	distributedTxCh chan TransactionDistributed
+	peerEventsCh    chan PeerEvent
 
 	// key - hostname:port
-	// TODO: add info about regions as well
 	clients   map[string]hostInfo
 	clientsMu sync.RWMutex
+
+	store       TxStore
+	offsetStore OffsetStore
+	snapshots   SnapshotSource
+
+	region string
+	quorum QuorumPolicy
+
+	replicaCfg replicaConfig
+
+	// nil - no tls
+	tls *certReloader
+
+	selector ReplicaSelector
+	// 0 - no speculative sends
+	speculateAfter time.Duration
+}
+
+// txs per peer that are not acked yet
+const DefaultQueueSize = 4096
+
+// by default grpc waits up to 2 minutes between reconnects
+// hints are replayed once the connection is ready, so it shouldn't take that long
+var connectParams = grpc.ConnectParams{
+	Backoff: backoff.Config{
+		BaseDelay:  minReconnectBackoff,
+		Multiplier: backoff.DefaultConfig.Multiplier,
+		Jitter:     backoff.DefaultConfig.Jitter,
+		MaxDelay:   maxReconnectBackoff,
+	},
+	MinConnectTimeout: 5 * time.Second,
 }
 
 type hostInfo struct {
 	client     maroonv1.P2PServiceClient
 	connection *grpc.ClientConn
+	region     string
+	replica    *replica
 }
 
 // wanted to explicitly return transactionDistributed channel here
 // to highlight uniqueness of ownership.
 //   - so it will be not possible to get channel in many places and consume and block it
 //   - makes sense?
-func New(dnsName string, port string) (Transport, chan TransactionDistributed) {
+func New(dnsName string, port string, opts ...Option) (Transport, chan TransactionDistributed) {
 	distributedCh := make(chan TransactionDistributed)
-	return &serv{
-		port:                port,
-		toDistributeQueueCh: make(chan Transaction),
-		distributedTxCh:     distributedCh,
-	}, distributedCh
+	s := &serv{
+		port:            port,
+		nodeID:          dnsName,
+		distributedTxCh: distributedCh,
+		peerEventsCh:    make(chan PeerEvent, peerEventsBuffer),
+		quorum:          AckCount(2),
+		selector:        SendToAll(),
+		replicaCfg: replicaConfig{
+			queueSize: DefaultQueueSize,
+			overflow:  OverflowReject,
+			hintAfter: defaultHintAfter,
+			maxHints:  defaultMaxHints,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.tls != nil {
+		// not fatal, the files could appear later
+		if _, _, err := s.tls.get(); err != nil {
+			logger.Errorf(logger.Network, "%v", err)
+		}
+	}
+	return s, distributedCh
+}
+
+// puts the transaction into the queues of the peers chosen by ReplicaSelector
+// the queues are sent over the replication streams, see replication.go
+func (s *serv) DistributeTx(m Transaction) error {
+	s.clientsMu.RLock()
+	regions := map[string]bool{s.region: true}
+	replicas := make(map[string]*replica, len(s.clients))
+	peers := make([]PeerHealth, 0, len(s.clients))
+	for host, hostI := range s.clients {
+		regions[hostI.region] = true
+		replicas[host] = hostI.replica
+		peers = append(peers, hostI.replica.health())
+	}
+	s.clientsMu.RUnlock()
+	slices.SortFunc(peers, func(a, b PeerHealth) int { return strings.Compare(a.Addr, b.Addr) })
+	primary, spare := s.selector.Select(peers)
+
+	tracker := &ackTracker{
+		acks:    make(map[string]int),
+		local:   s.region,
+		regions: len(regions),
+		quorum:  s.quorum,
+		start:   time.Now(),
+	}
+	// the queue can block, so it's done without the lock
+	queued := make(map[string]int)
+	sent, rejected := 0, 0
+	send := func(p PeerHealth) {
+		sent++
+		if replicas[p.Addr].enqueue(m, tracker) {
+			queued[p.Region]++
+		} else {
+			rejected++
+		}
+	}
+	for _, p := range primary {
+		send(p)
+	}
+	// primary ones can't make the quorum
+	for len(spare) > 0 && !s.quorum.Reached(queued, s.region, len(regions)) {
+		send(spare[0])
+		spare = spare[1:]
+	}
+
+	if rejected > 0 && !s.quorum.Reached(queued, s.region, len(regions)) {
+		return fmt.Errorf("%w: tx %v is queued for %d of %d peers", ErrQueueFull, m.ID, sent-rejected, sent)
+	}
+	if s.speculateAfter > 0 && len(spare) > 0 {
+		s.speculate(m, tracker, replicas, spare)
+	}
+	return nil
 }
 
-func (s *serv) DistributeTx(m Transaction) {
-	go func() {
-		s.toDistributeQueueCh <- m
-	}()
+func (s *serv) SetTxStore(store TxStore) {
+	s.store = store
+}
+
+func (s *serv) FetchTxs(ctx context.Context, ids []string) ([]Transaction, error) {
+	missing := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		missing[id] = true
+	}
+
+	var found []Transaction
+	// unhealthy peers are asked last
+	for _, peer := range s.peersByHealth() {
+		if len(missing) == 0 {
+			break
+		}
+		host, client := peer.host, peer.client
+
+		req := &maroonv1.GetTxsRequest{}
+		for id := range missing {
+			req.Ids = append(req.Ids, id)
+		}
+		resp, err := client.GetTxs(ctx, req)
+		if err != nil {
+			logger.Warningf(logger.Network, "failed to get txs from %v: %v", host, err)
+			continue
+		}
+		for _, tx := range resp.Txs {
+			if !missing[tx.Id] {
+				continue
+			}
+			delete(missing, tx.Id)
+			found = append(found, Transaction{
+				ID:     tx.Id,
+				TxData: tx.Payload,
+			})
+		}
+	}
+
+	if len(missing) > 0 {
+		return found, fmt.Errorf("%d of %d txs are not found on peers", len(missing), len(ids))
+	}
+	return found, nil
+}
+
+func (s *serv) FetchPendingTxs(ctx context.Context) ([]Transaction, error) {
+	s.clientsMu.RLock()
+	peers := make(map[string]maroonv1.P2PServiceClient, len(s.clients))
+	for host, hostI := range s.clients {
+		peers[host] = hostI.client
+	}
+	s.clientsMu.RUnlock()
+
+	seen := make(map[string]bool)
+	var found []Transaction
+	var errs []error
+	for host, client := range peers {
+		resp, err := client.GetPendingTxs(ctx, &maroonv1.GetPendingTxsRequest{})
+		if err != nil {
+			logger.Warningf(logger.Network, "failed to get pending txs from %v: %v", host, err)
+			errs = append(errs, err)
+			continue
+		}
+		for _, tx := range resp.Txs {
+			if seen[tx.Id] {
+				continue
+			}
+			seen[tx.Id] = true
+			found = append(found, Transaction{
+				ID:     tx.Id,
+				TxData: tx.Payload,
+			})
+		}
+	}
+
+	if len(peers) > 0 && len(errs) == len(peers) {
+		return nil, fmt.Errorf("no peer answered: %w", errors.Join(errs...))
+	}
+	return found, nil
 }
 
 // for new hosts - will establish a new connection
 // for removed hosts - will close the connection
 // for unchanged - will do nothing
-func (s *serv) UpdateHosts(newHosts []string) {
+func (s *serv) UpdateHosts(newHosts []Peer) {
 	s.clientsMu.Lock()
 	defer s.clientsMu.Unlock()
 
@@ -69,70 +257,92 @@ func (s *serv) UpdateHosts(newHosts []string) {
 		currentHosts[host] = true
 	}
 
-	for _, host := range newHosts {
-		if _, exists := s.clients[host]; !exists {
-			conn, err := grpc.NewClient(host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	for _, peer := range newHosts {
+		host := peer.Addr
+		if hostI, exists := s.clients[host]; !exists {
+			creds := insecure.NewCredentials()
+			if s.tls != nil {
+				name, _, err := net.SplitHostPort(host)
+				if err != nil {
+					name = host
+				}
+				creds = credentials.NewTLS(s.clientTLSConfig(name))
+			}
+			conn, err := grpc.NewClient(host,
+				grpc.WithTransportCredentials(creds),
+				grpc.WithConnectParams(connectParams),
+			)
 			if err != nil {
 				// TODO: proper error handling
 				logger.Errorf(logger.Network, "failed to establish peer connection host: %v err: %v", host, err)
 				continue
 			}
-			logger.Infof(logger.Network, "connection established: %v", host)
+			logger.Infof(logger.Network, "connection established: %v region: %q", host, peer.Region)
+			client := maroonv1.NewP2PServiceClient(conn)
 			s.clients[host] = hostInfo{
-				maroonv1.NewP2PServiceClient(conn),
-				conn,
+				client:     client,
+				connection: conn,
+				region:     peer.Region,
+				replica:    newReplica(host, peer.Region, conn, s.distributedTxCh, s.peerEventsCh, s.replicaCfg),
 			}
+		} else if hostI.region != peer.Region {
+			hostI.region = peer.Region
+			hostI.replica.setRegion(peer.Region)
+			s.clients[host] = hostI
 		}
 		delete(currentHosts, host)
 	}
 
 	// Remove clients that are no longer in the new hosts list
 	for host := range currentHosts {
+		s.clients[host].replica.stop()
 		if err := s.clients[host].connection.Close(); err != nil {
 			logger.Errorf(logger.Network, "failed to close peer connection: %v: %v", host, err)
 		}
 		delete(s.clients, host)
 	}
-}
 
-func (s *serv) serveOutboundMessageQueue() {
-	for tx := range s.toDistributeQueueCh {
-
-		ctx := context.TODO()
-		// TODO: some algorithm on how to distribute
-		// which nodes/regions/etc
-
-		// TODO: that lock is not right thing here
-		// I need to find a better way of replacing it
-		s.clientsMu.RLock()
-		var counterDistributed atomic.Int32
-		for _, hostI := range s.clients {
-			client := hostI.client
-			logger.Errorf(logger.Network, "connection state: %v", hostI.connection.GetState().String())
-			go func() {
-				resp, err := client.AddTx(ctx, &maroonv1.AddTxRequest{
-					Id:      tx.ID,
-					Payload: tx.TxData,
-				})
-				if err != nil {
-					logger.Errorf(logger.Network, "failed to send addTX message: %v", err)
-				} else {
-					log.Println(tx.ID, "resp", resp, "err", err)
-
-					// TODO: that's a very naive way of checking that it was distributed
-					// rethink it later and take regions into consideration
-					counterDistributed.Add(1)
-					if counterDistributed.Load() >= 2 {
-						s.distributedTxCh <- TransactionDistributed{
-							ID: tx.ID,
-						}
-						log.Println(tx.ID, "resp after put to distributed channel")
-					}
-				}
-			}()
-		}
+	// even if every peer acks
+	sizes := make(map[string]int)
+	regions := map[string]bool{s.region: true}
+	for _, hostI := range s.clients {
+		sizes[hostI.region]++
+		regions[hostI.region] = true
+	}
+	if !s.quorum.Reached(sizes, s.region, len(regions)) {
+		logger.Errorf(logger.Network, "quorum can't be reached with %d peers in %d regions: %v", len(s.clients), len(regions), sizes)
+	}
+}
 
-		s.clientsMu.RUnlock()
+// counts acks of a single transaction
+type ackTracker struct {
+	mu      sync.Mutex
+	acks    map[string]int
+	local   string
+	regions int
+	quorum  QuorumPolicy
+	reached bool
+	// when the transaction was distributed
+	start time.Time
+}
 
+// returns true only once - when the quorum is reached
+func (t *ackTracker) ack(region string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.acks[region]++
+	if t.reached || !t.quorum.Reached(t.acks, t.local, t.regions) {
+		return false
 	}
+	t.reached = true
+	if !t.start.IsZero() {
+		observeDistribution(time.Since(t.start))
+	}
+	return true
+}
+
+func (t *ackTracker) isReached() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reached
 }
@@ -0,0 +1,57 @@
+package p2p
+
+// decides when a transaction is stored on enough nodes
+// to notify the leader with TransactionDistributed
+type QuorumPolicy interface {
+	// acks - amount of peers per region that acked the transaction, the node itself is not counted
+	// local - region of the node itself, it has the transaction without any ack
+	// regions - amount of regions known to the node including its own one
+	Reached(acks map[string]int, local string, regions int) bool
+}
+
+type ackCount struct {
+	n int
+}
+
+// any n acks of the peers regardless of regions
+func AckCount(n int) QuorumPolicy {
+	return ackCount{n: n}
+}
+
+func (q ackCount) Reached(acks map[string]int, _ string, _ int) bool {
+	total := 0
+	for _, c := range acks {
+		total += c
+	}
+	return total >= q.n
+}
+
+type regionMajority struct {
+	nodesPerRegion int
+}
+
+// rule from some.txt
+// acks from nodesPerRegion nodes in at least (R+1)/2 regions
+// so the transaction survives the loss of the whole region
+// the node itself is one of the nodes of its region
+func RegionMajority(nodesPerRegion int) QuorumPolicy {
+	return regionMajority{nodesPerRegion: nodesPerRegion}
+}
+
+func (q regionMajority) Reached(acks map[string]int, local string, regions int) bool {
+	needRegions := (regions + 1) / 2
+	if needRegions < 1 {
+		needRegions = 1
+	}
+
+	ackedRegions := 0
+	if acks[local]+1 >= q.nodesPerRegion {
+		ackedRegions++
+	}
+	for region, c := range acks {
+		if region != local && c >= q.nodesPerRegion {
+			ackedRegions++
+		}
+	}
+	return ackedRegions >= needRegions
+}
@@ -0,0 +1,53 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionMajority(t *testing.T) {
+	q := RegionMajority(2)
+
+	// 3 regions - need 2 of them with 2 nodes each, the node itself is in eu
+	require.False(t, q.Reached(map[string]int{"us": 2}, "eu", 3))
+	require.False(t, q.Reached(map[string]int{"us": 1, "asia": 1}, "eu", 3))
+	require.True(t, q.Reached(map[string]int{"eu": 1, "us": 2}, "eu", 3))
+	require.True(t, q.Reached(map[string]int{"us": 2, "asia": 2}, "eu", 3))
+
+	// single region
+	require.False(t, q.Reached(map[string]int{}, "eu", 1))
+	require.True(t, q.Reached(map[string]int{"eu": 1}, "eu", 1))
+
+	// 5 regions - need 3
+	require.False(t, q.Reached(map[string]int{"a": 5, "b": 5}, "c", 5))
+	require.True(t, q.Reached(map[string]int{"a": 2, "b": 2, "c": 1}, "c", 5))
+}
+
+func TestRegionMajorityCountsLocalNode(t *testing.T) {
+	// the leader's region has only one more node
+	q := RegionMajority(2)
+	require.True(t, q.Reached(map[string]int{"eu": 1}, "eu", 1))
+	require.False(t, RegionMajority(3).Reached(map[string]int{"eu": 1}, "eu", 1))
+}
+
+func TestAckCount(t *testing.T) {
+	q := AckCount(2)
+	require.False(t, q.Reached(map[string]int{"eu": 1}, "eu", 2))
+	require.True(t, q.Reached(map[string]int{"eu": 1, "us": 1}, "eu", 2))
+}
+
+func TestAckTrackerNotifiesOnce(t *testing.T) {
+	tracker := &ackTracker{
+		acks:    make(map[string]int),
+		local:   "eu",
+		regions: 3,
+		quorum:  RegionMajority(2),
+	}
+
+	require.False(t, tracker.ack("us"))
+	// eu has 2 with the node itself
+	require.False(t, tracker.ack("eu"))
+	require.True(t, tracker.ack("us"))
+	require.False(t, tracker.ack("asia"))
+}
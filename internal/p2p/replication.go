@@ -0,0 +1,578 @@
+package p2p
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// txs in a single ReplicateRequest
+	maxReplicateBatch = 128
+	// sent but not acked txs per peer, the leader waits for acks after that
+	maxInFlight = 1024
+
+	minReconnectBackoff = 100 * time.Millisecond
+	maxReconnectBackoff = 5 * time.Second
+
+	// rtt of idle peers is measured that often
+	probeInterval = time.Second
+
+	// peer that is down for longer gets txs as hints
+	defaultHintAfter = 10 * time.Second
+	defaultMaxHints  = 65536
+)
+
+// the same for every peer
+type replicaConfig struct {
+	// bounds the queue of not acked txs
+	queueSize int
+	overflow  OverflowPolicy
+	// 0 - no hints, the queue is used while the peer is down
+	hintAfter time.Duration
+	maxHints  int
+}
+
+// follower side
+// stores txs in the order they come and acks everything up to the last stored one
+func (s *serv) Replicate(stream grpc.BidiStreamingServer[maroonv1.ReplicateRequest, maroonv1.ReplicateAck]) error {
+	if s.store == nil {
+		return status.Error(codes.Unavailable, "node is not ready to store transactions")
+	}
+
+	// the leader keeps the stream open even when there is nothing to send
+	// so it's closed here when the node stops, otherwise graceful stop waits forever
+	reqCh := make(chan *maroonv1.ReplicateRequest)
+	recvErrCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErrCh <- err
+				return
+			}
+			select {
+			case reqCh <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		var req *maroonv1.ReplicateRequest
+		select {
+		case <-s.stoppingCh:
+			return status.Error(codes.Unavailable, "node is stopping")
+		case err := <-recvErrCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case req = <-reqCh:
+		}
+
+		for i, tx := range req.Txs {
+			err := s.store.StoreTx(Transaction{
+				ID:     tx.Id,
+				TxData: tx.Payload,
+			})
+			if err == nil {
+				continue
+			}
+			logger.Errorf(logger.Network, "failed to store tx %v: %v", tx.Id, err)
+			if errors.Is(err, ErrBadTx) {
+				// the ack tells the leader which tx is rejected, even if it's the first one
+				if err := stream.Send(&maroonv1.ReplicateAck{AckedSeq: req.FirstSeq + uint64(i) - 1}); err != nil {
+					return err
+				}
+				return status.Errorf(codes.InvalidArgument, "tx %v is rejected: %v", tx.Id, err)
+			}
+			if i > 0 {
+				// the ones before are stored
+				if err := stream.Send(&maroonv1.ReplicateAck{AckedSeq: req.FirstSeq + uint64(i) - 1}); err != nil {
+					return err
+				}
+			}
+			// leader sends the rest again after a reconnect
+			return status.Errorf(codes.Internal, "failed to store tx %v: %v", tx.Id, err)
+		}
+
+		if len(req.Txs) == 0 {
+			continue
+		}
+		if err := stream.Send(&maroonv1.ReplicateAck{AckedSeq: req.FirstSeq + uint64(len(req.Txs)) - 1}); err != nil {
+			return err
+		}
+	}
+}
+
+// leader side
+// keeps the txs for a single peer until it acks them
+// one stream per peer, txs go in the order they were distributed
+// while the peer is down for longer than hintAfter, new txs go to hints instead of the queue
+// they don't count for the quorum and are replayed once the peer is back
+type replica struct {
+	host   string
+	conn   *grpc.ClientConn
+	client maroonv1.P2PServiceClient
+	// TransactionDistributed goes there
+	distributedTxCh chan<- TransactionDistributed
+	// health changes go there, see health.go
+	eventsCh chan<- PeerEvent
+	cfg      replicaConfig
+
+	mu     sync.Mutex
+	region string
+	// not acked yet, in seq order
+	pending []pendingTx
+	// seq of the next distributed tx
+	nextSeq uint64
+	// the last ack of the peer
+	acked uint64
+	// signalled when pending gets shorter or the replica stops
+	roomCond *sync.Cond
+	stopped  bool
+	// zero while the stream is open
+	downSince time.Time
+	// go after pending, seq isn't set yet
+	hints []pendingTx
+	// replayed hints in pending, they don't count for queueSize
+	replaying int
+
+	// health
+	connState connectivity.State
+	lastAck   time.Time
+	rtt       time.Duration
+	failures  int
+	// last seq of every batch in flight and when it was sent
+	sentAt []sentBatch
+
+	// something to send or an ack, capacity 1
+	wakeCh chan struct{}
+	// connection became ready, capacity 1
+	readyCh chan struct{}
+	cancel  context.CancelFunc
+	doneCh  chan struct{}
+}
+
+type sentBatch struct {
+	seq uint64
+	at  time.Time
+}
+
+type pendingTx struct {
+	seq uint64
+	tx  Transaction
+	// nil for hints, they don't count for the quorum
+	tracker *ackTracker
+}
+
+func newReplica(host, region string, conn *grpc.ClientConn, distributedTxCh chan<- TransactionDistributed, eventsCh chan<- PeerEvent, cfg replicaConfig) *replica {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &replica{
+		host:            host,
+		conn:            conn,
+		client:          maroonv1.NewP2PServiceClient(conn),
+		distributedTxCh: distributedTxCh,
+		eventsCh:        eventsCh,
+		cfg:             cfg,
+		region:          region,
+		connState:       conn.GetState(),
+		nextSeq:         1,
+		// it's up once the first stream is open
+		downSince: time.Now(),
+		wakeCh:    make(chan struct{}, 1),
+		readyCh:   make(chan struct{}, 1),
+		cancel:    cancel,
+		doneCh:    make(chan struct{}),
+	}
+	r.roomCond = sync.NewCond(&r.mu)
+	go r.watchConn(ctx)
+	go r.probe(ctx)
+	go r.run(ctx)
+	return r
+}
+
+// returns false if the tx is not queued for the peer
+// hinted txs are not queued either
+func (r *replica) enqueue(tx Transaction, tracker *ackTracker) bool {
+	r.mu.Lock()
+	if !r.stopped && r.hinting() {
+		if len(r.hints) >= r.cfg.maxHints {
+			logger.Warningf(logger.Network, "too many hints for %v, tx %v is dropped", r.host, r.hints[0].tx.ID)
+			r.hints = r.hints[1:]
+		}
+		// DistributeTx doesn't count it, so the peer doesn't either
+		r.hints = append(r.hints, pendingTx{tx: tx})
+		r.mu.Unlock()
+		return false
+	}
+	for !r.stopped && len(r.pending)-r.replaying >= r.cfg.queueSize {
+		switch r.cfg.overflow {
+		case OverflowBlock:
+			r.roomCond.Wait()
+			continue
+		case OverflowDropOldest:
+			if r.dropOldest() {
+				continue
+			}
+		}
+		r.mu.Unlock()
+		return false
+	}
+	if r.stopped {
+		r.mu.Unlock()
+		return false
+	}
+	r.pending = append(r.pending, pendingTx{seq: r.nextSeq, tx: tx, tracker: tracker})
+	r.nextSeq++
+	r.mu.Unlock()
+	r.wake()
+	return true
+}
+
+// the oldest queued tx, replayed hints stay
+// returns false if there are only hints
+// should be called under mu
+func (r *replica) dropOldest() bool {
+	i := 0
+	for i < len(r.pending) && r.pending[i].tracker == nil {
+		i++
+	}
+	if i == len(r.pending) {
+		return false
+	}
+	logger.Warningf(logger.Network, "queue to %v is full, tx %v is dropped", r.host, r.pending[i].tx.ID)
+	r.pending = slices.Delete(r.pending, i, i+1)
+	return true
+}
+
+// should be called under mu
+func (r *replica) hinting() bool {
+	return r.cfg.hintAfter > 0 && r.cfg.maxHints > 0 && !r.downSince.IsZero() && time.Since(r.downSince) > r.cfg.hintAfter
+}
+
+func (r *replica) setRegion(region string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.region = region
+}
+
+func (r *replica) getRegion() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.region
+}
+
+// unacked txs and hints are dropped
+func (r *replica) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.roomCond.Broadcast()
+	r.mu.Unlock()
+
+	r.cancel()
+	<-r.doneCh
+	r.updateHealth(func() {
+		r.connState = connectivity.Shutdown
+	})
+}
+
+func (r *replica) wake() {
+	select {
+	case r.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// reconnects until stopped
+// right away when the connection is ready again, otherwise after a backoff
+func (r *replica) run(ctx context.Context) {
+	defer close(r.doneCh)
+
+	backoff := minReconnectBackoff
+	for {
+		progress, err := r.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if progress {
+			backoff = minReconnectBackoff
+		}
+		if status.Code(err) == codes.InvalidArgument && r.skipRejected() {
+			// the peer is fine, the rest goes right away
+			continue
+		}
+		r.updateHealth(func() {
+			r.failures++
+			if r.downSince.IsZero() {
+				r.downSince = time.Now()
+			}
+		})
+
+		wait := jittered(backoff)
+		logger.Warningf(logger.Network, "replication stream to %v is broken, reconnect in %v: %v", r.host, wait, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.readyCh:
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxReconnectBackoff)
+	}
+}
+
+// somewhere in [d/2, d), so the peers don't reconnect all at once
+func jittered(d time.Duration) time.Duration {
+	return d/2 + rand.N(d/2+1)
+}
+
+// measures rtt while there are no acks, otherwise an idle peer is never chosen by rtt
+func (r *replica) probe(ctx context.Context) {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		r.mu.Lock()
+		idle := time.Since(r.lastAck) > probeInterval
+		r.mu.Unlock()
+		if !idle {
+			continue
+		}
+
+		probeCtx, cancel := context.WithTimeout(ctx, probeInterval)
+		start := time.Now()
+		_, err := r.client.GetTxs(probeCtx, &maroonv1.GetTxsRequest{})
+		cancel()
+		if err != nil {
+			// broken streams count as failures, probes don't
+			continue
+		}
+		r.updateHealth(func() {
+			r.sampleRTT(time.Since(start))
+		})
+	}
+}
+
+func (r *replica) watchConn(ctx context.Context) {
+	state := r.conn.GetState()
+	for {
+		r.updateHealth(func() {
+			// Shutdown is already set
+			if !r.stopped {
+				r.connState = state
+			}
+		})
+		switch state {
+		case connectivity.Ready:
+			select {
+			case r.readyCh <- struct{}{}:
+			default:
+			}
+		case connectivity.Idle:
+			// nothing reconnects an idle connection otherwise
+			r.conn.Connect()
+		}
+		if !r.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		state = r.conn.GetState()
+	}
+}
+
+// sends txs over a single stream until it breaks
+// returns true if some txs were acked
+func (r *replica) stream(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := r.client.Replicate(ctx)
+	if err != nil {
+		return false, err
+	}
+	r.up()
+
+	var progress atomic.Bool
+	ackErrCh := make(chan error, 1)
+	go func() {
+		for {
+			ack, err := stream.Recv()
+			if err != nil {
+				ackErrCh <- err
+				return
+			}
+			progress.Store(true)
+			r.ack(ack.AckedSeq)
+		}
+	}()
+
+	// everything that wasn't acked goes again
+	r.mu.Lock()
+	sent := r.nextSeq - 1
+	if len(r.pending) > 0 {
+		sent = r.pending[0].seq - 1
+	}
+	r.mu.Unlock()
+
+	for {
+		req := r.nextBatch(sent)
+		if req == nil {
+			select {
+			case <-ctx.Done():
+				return progress.Load(), ctx.Err()
+			case err := <-ackErrCh:
+				if err == io.EOF {
+					err = errors.New("stream closed by the peer")
+				}
+				return progress.Load(), err
+			case <-r.wakeCh:
+				continue
+			}
+		}
+
+		if err := stream.Send(req); err != nil {
+			if err == io.EOF {
+				// the stream is aborted by the peer, the reason comes from Recv
+				err = <-ackErrCh
+			}
+			return progress.Load(), err
+		}
+		sent = req.FirstSeq + uint64(len(req.Txs)) - 1
+		r.mu.Lock()
+		r.sentAt = append(r.sentAt, sentBatch{seq: sent, at: time.Now()})
+		r.mu.Unlock()
+	}
+}
+
+// the stream is open, hints go to the queue
+func (r *replica) up() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.downSince = time.Time{}
+	// acks of the previous stream don't come anymore
+	r.sentAt = nil
+	if len(r.hints) == 0 {
+		return
+	}
+	logger.Infof(logger.Network, "%v is back, replaying %d hints", r.host, len(r.hints))
+	// the queue gets longer than queueSize for a while, new txs are queued as usual
+	for _, h := range r.hints {
+		h.seq = r.nextSeq
+		r.nextSeq++
+		r.pending = append(r.pending, h)
+	}
+	r.replaying += len(r.hints)
+	r.hints = nil
+}
+
+// txs after the sent one, nil if there is nothing or too many are not acked
+func (r *replica) nextBatch(sent uint64) *maroonv1.ReplicateRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 {
+		return nil
+	}
+
+	// sent ones could've been dropped from the queue already
+	first, _ := slices.BinarySearchFunc(r.pending, sent+1, func(p pendingTx, seq uint64) int {
+		return cmp.Compare(p.seq, seq)
+	})
+	if first >= min(len(r.pending), maxInFlight) {
+		return nil
+	}
+	last := first + 1
+	// the peer acks FirstSeq + len(Txs) - 1, so seqs in a batch go one by one
+	// dropped txs leave gaps between replayed hints and new txs
+	for last < min(len(r.pending), first+maxReplicateBatch, maxInFlight) && r.pending[last].seq == r.pending[last-1].seq+1 {
+		last++
+	}
+
+	req := &maroonv1.ReplicateRequest{FirstSeq: r.pending[first].seq}
+	for _, p := range r.pending[first:last] {
+		req.Txs = append(req.Txs, &maroonv1.Tx{
+			Id:      p.tx.ID,
+			Payload: p.tx.TxData,
+		})
+	}
+	return req
+}
+
+func (r *replica) ack(seq uint64) {
+	r.mu.Lock()
+	n := 0
+	for n < len(r.pending) && r.pending[n].seq <= seq {
+		n++
+	}
+	acked := r.pending[:n]
+	r.pending = r.pending[n:]
+	r.acked = seq
+	for _, p := range acked {
+		if p.tracker == nil {
+			r.replaying--
+		}
+	}
+	region := r.region
+	if n > 0 {
+		r.roomCond.Broadcast()
+	}
+	r.mu.Unlock()
+
+	now := time.Now()
+	r.updateHealth(func() {
+		r.lastAck = now
+		r.failures = 0
+		// the latest acked batch is the most precise one
+		b := 0
+		for b < len(r.sentAt) && r.sentAt[b].seq <= seq {
+			b++
+		}
+		if b > 0 {
+			r.sampleRTT(now.Sub(r.sentAt[b-1].at))
+			r.sentAt = r.sentAt[b:]
+		}
+	})
+	// window has moved
+	r.wake()
+
+	for _, p := range acked {
+		if p.tracker != nil && p.tracker.ack(region) {
+			r.distributedTxCh <- TransactionDistributed{ID: p.tx.ID}
+		}
+	}
+}
+
+// the peer can't store the tx after the acked ones, it would get it again after every reconnect
+// it doesn't count for the quorum, the peer fetches it with the block if the others make it
+// returns false if it's not in the queue anymore
+func (r *replica) skipRejected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending) == 0 || r.pending[0].seq != r.acked+1 {
+		return false
+	}
+	logger.Errorf(logger.Network, "tx %v is rejected by %v, skipped", r.pending[0].tx.ID, r.host)
+	if r.pending[0].tracker == nil {
+		r.replaying--
+	}
+	r.pending = r.pending[1:]
+	r.roomCond.Broadcast()
+	return true
+}
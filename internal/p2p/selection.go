@@ -0,0 +1,130 @@
+package p2p
+
+import (
+	"cmp"
+	"expvar"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// decides which peers get a transaction
+// the transport adds spare peers when the primary ones can't make the quorum,
+// and one more spare peer every time the acks are late, see WithSpeculativeSend
+type ReplicaSelector interface {
+	// peers - all the peers, sorted by address
+	// primary get the transaction right away, spare are used in the given order
+	Select(peers []PeerHealth) (primary, spare []PeerHealth)
+}
+
+type sendToAll struct{}
+
+// every peer gets every transaction
+func SendToAll() ReplicaSelector {
+	return sendToAll{}
+}
+
+func (sendToAll) Select(peers []PeerHealth) ([]PeerHealth, []PeerHealth) {
+	return peers, nil
+}
+
+type fastestK struct {
+	k int
+}
+
+// k peers with the lowest rtt
+// negative k is the same as 0, all the peers are spare then
+func FastestK(k int) ReplicaSelector {
+	return fastestK{k: max(k, 0)}
+}
+
+func (s fastestK) Select(peers []PeerHealth) ([]PeerHealth, []PeerHealth) {
+	sorted := byLatency(peers)
+	k := min(s.k, len(sorted))
+	return sorted[:k], sorted[k:]
+}
+
+type nearestPerRegion struct{}
+
+// a peer with the lowest rtt in every region
+// with RegionMajority(n > 1) the rest of the quorum comes from the spare ones
+func NearestPerRegion() ReplicaSelector {
+	return nearestPerRegion{}
+}
+
+func (nearestPerRegion) Select(peers []PeerHealth) ([]PeerHealth, []PeerHealth) {
+	var primary, spare []PeerHealth
+	seen := make(map[string]bool)
+	for _, p := range byLatency(peers) {
+		if seen[p.Region] {
+			spare = append(spare, p)
+			continue
+		}
+		seen[p.Region] = true
+		primary = append(primary, p)
+	}
+	return primary, spare
+}
+
+// healthy first, then by rtt, peers without rtt go after the measured ones
+func byLatency(peers []PeerHealth) []PeerHealth {
+	sorted := slices.Clone(peers)
+	slices.SortStableFunc(sorted, func(a, b PeerHealth) int {
+		if a.Healthy != b.Healthy {
+			if a.Healthy {
+				return -1
+			}
+			return 1
+		}
+		if (a.RTT == 0) != (b.RTT == 0) {
+			if a.RTT == 0 {
+				return 1
+			}
+			return -1
+		}
+		return cmp.Compare(a.RTT, b.RTT)
+	})
+	return sorted
+}
+
+var (
+	// time between DistributeTx and the quorum, counts per bucket
+	distributionLatency = expvar.NewMap("p2p_distribution_latency_ms")
+	speculativeSends    = expvar.NewInt("p2p_speculative_sends")
+
+	latencyBuckets = []time.Duration{
+		time.Millisecond,
+		2 * time.Millisecond,
+		5 * time.Millisecond,
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		500 * time.Millisecond,
+		time.Second,
+	}
+)
+
+func observeDistribution(d time.Duration) {
+	distributionLatency.Add("sum", d.Milliseconds())
+	for _, b := range latencyBuckets {
+		if d <= b {
+			distributionLatency.Add(fmt.Sprintf("le_%04d", b.Milliseconds()), 1)
+			return
+		}
+	}
+	distributionLatency.Add("le_inf", 1)
+}
+
+// one more spare peer gets the transaction every time the acks are late
+func (s *serv) speculate(m Transaction, tracker *ackTracker, replicas map[string]*replica, spare []PeerHealth) {
+	time.AfterFunc(s.speculateAfter, func() {
+		if len(spare) == 0 || tracker.isReached() {
+			return
+		}
+		speculativeSends.Add(1)
+		replicas[spare[0].Addr].enqueue(m, tracker)
+		s.speculate(m, tracker, replicas, spare[1:])
+	})
+}
@@ -0,0 +1,46 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func addrs(peers []PeerHealth) []string {
+	res := []string{}
+	for _, p := range peers {
+		res = append(res, p.Addr)
+	}
+	return res
+}
+
+var testPeers = []PeerHealth{
+	{Peer: Peer{Addr: "a", Region: "eu"}, RTT: 30 * time.Millisecond, Healthy: true},
+	{Peer: Peer{Addr: "b", Region: "eu"}, RTT: 5 * time.Millisecond, Healthy: true},
+	// fastest but down
+	{Peer: Peer{Addr: "c", Region: "us"}, RTT: time.Millisecond},
+	// not measured yet
+	{Peer: Peer{Addr: "d", Region: "us"}, Healthy: true},
+	{Peer: Peer{Addr: "e", Region: "us"}, RTT: 80 * time.Millisecond, Healthy: true},
+}
+
+func TestFastestK(t *testing.T) {
+	primary, spare := FastestK(2).Select(testPeers)
+	require.Equal(t, []string{"b", "a"}, addrs(primary))
+	require.Equal(t, []string{"e", "d", "c"}, addrs(spare))
+
+	primary, spare = FastestK(10).Select(testPeers)
+	require.Len(t, primary, 5)
+	require.Empty(t, spare)
+
+	primary, spare = FastestK(-1).Select(testPeers)
+	require.Empty(t, primary)
+	require.Len(t, spare, 5)
+}
+
+func TestNearestPerRegion(t *testing.T) {
+	primary, spare := NearestPerRegion().Select(testPeers)
+	require.Equal(t, []string{"b", "e"}, addrs(primary))
+	require.Equal(t, []string{"a", "d", "c"}, addrs(spare))
+}
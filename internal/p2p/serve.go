@@ -6,6 +6,7 @@ import (
 
 	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // Blocking function
@@ -14,13 +15,16 @@ func (s *serv) Start() {
 	if err != nil {
 		panic(err)
 	}
-	grpcServ := grpc.NewServer()
+	var opts []grpc.ServerOption
+	if s.tls != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.serverTLSConfig())))
+	}
+	grpcServ := grpc.NewServer(opts...)
 	s.grpc = grpcServ
+	s.stoppingCh = make(chan struct{})
 
 	maroonv1.RegisterP2PServiceServer(grpcServ, s)
 
-	go s.serveOutboundMessageQueue()
-
 	if err := grpcServ.Serve(lis); err != nil {
 		panic(err)
 	}
@@ -31,6 +35,13 @@ func (s *serv) Stop() {
 	if s.grpc == nil {
 		return
 	}
+	close(s.stoppingCh)
 	s.grpc.GracefulStop()
 	s.grpc = nil
+
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	for _, hostI := range s.clients {
+		hostI.replica.stop()
+	}
 }
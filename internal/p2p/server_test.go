@@ -1,20 +1,31 @@
 package p2p
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
 )
 
 func TestTransportCommunication(t *testing.T) {
 	leader, distributedCh := New("localhost", "8081")
 	f1, _ := New("localhost", "8082")
 	f2, _ := New("localhost", "8083")
+	f1Store, f2Store := newMemStore(), newMemStore()
+	f1.SetTxStore(f1Store)
+	f2.SetTxStore(f2Store)
 
-	leader.UpdateHosts([]string{"localhost:8082", "localhost:8083"})
-	f1.UpdateHosts([]string{"localhost:8081", "localhost:8083"})
-	f2.UpdateHosts([]string{"localhost:8081", "localhost:8082"})
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8082"}, {Addr: "localhost:8083"}})
+	f1.UpdateHosts([]Peer{{Addr: "localhost:8081"}, {Addr: "localhost:8083"}})
+	f2.UpdateHosts([]Peer{{Addr: "localhost:8081"}, {Addr: "localhost:8082"}})
 
 	go leader.Start()
 	go f1.Start()
@@ -33,5 +44,529 @@ func TestTransportCommunication(t *testing.T) {
 	time.Sleep(1 * time.Second)
 
 	require.ElementsMatch(t, []string{"tx-1", "tx-2"}, distributed)
+	require.Equal(t, []Transaction{{ID: "tx-1", TxData: []byte("hello-1")}}, f1Store.GetTxs([]string{"tx-1"}))
+	require.Equal(t, []Transaction{{ID: "tx-2", TxData: []byte("hello-2")}}, f2Store.GetTxs([]string{"tx-2"}))
 	t.Fail()
 }
+
+type memStore struct {
+	mu  sync.Mutex
+	txs map[string][]byte
+	// makes StoreTx fail
+	broken bool
+}
+
+func newMemStore(txs ...Transaction) *memStore {
+	m := &memStore{txs: make(map[string][]byte)}
+	for _, tx := range txs {
+		m.txs[tx.ID] = tx.TxData
+	}
+	return m
+}
+
+func (m *memStore) StoreTx(tx Transaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.broken {
+		return errors.New("disk is full")
+	}
+	m.txs[tx.ID] = tx.TxData
+	return nil
+}
+
+func (m *memStore) GetTxs(ids []string) []Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []Transaction
+	for _, id := range ids {
+		if data, ok := m.txs[id]; ok {
+			res = append(res, Transaction{ID: id, TxData: data})
+		}
+	}
+	return res
+}
+
+// everything in the store is pending
+func (m *memStore) PendingTxs() []Transaction {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var res []Transaction
+	for id, data := range m.txs {
+		res = append(res, Transaction{ID: id, TxData: data})
+	}
+	return res
+}
+
+func TestFetchTxsAcrossPeers(t *testing.T) {
+	follower, _ := New("localhost", "8091")
+	p1, _ := New("localhost", "8092")
+	p2, _ := New("localhost", "8093")
+
+	p1.SetTxStore(newMemStore(Transaction{ID: "tx-1", TxData: []byte("hello-1")}))
+	p2.SetTxStore(newMemStore(Transaction{ID: "tx-2", TxData: []byte("hello-2")}))
+	follower.UpdateHosts([]Peer{{Addr: "localhost:8092"}, {Addr: "localhost:8093"}})
+
+	go p1.Start()
+	go p2.Start()
+	defer p1.Stop()
+	defer p2.Stop()
+
+	require.Eventually(t, func() bool {
+		txs, err := follower.FetchTxs(context.Background(), []string{"tx-1", "tx-2"})
+		return err == nil && len(txs) == 2
+	}, time.Second, 50*time.Millisecond)
+
+	txs, err := follower.FetchTxs(context.Background(), []string{"tx-2", "tx-3"})
+	require.Error(t, err)
+	require.Equal(t, []Transaction{{ID: "tx-2", TxData: []byte("hello-2")}}, txs)
+}
+
+func TestNoConfirmationWithoutStoring(t *testing.T) {
+	leader, distributedCh := New("localhost", "8094")
+	f1, _ := New("localhost", "8095")
+	f2, _ := New("localhost", "8096")
+
+	f1.SetTxStore(newMemStore())
+	f2.SetTxStore(&memStore{broken: true})
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8095"}, {Addr: "localhost:8096"}})
+
+	go f1.Start()
+	go f2.Start()
+	go leader.Start()
+	defer f1.Stop()
+	defer f2.Stop()
+	defer leader.Stop()
+
+	leader.DistributeTx(Transaction{ID: "tx-1", TxData: []byte("hello-1")})
+
+	select {
+	case m := <-distributedCh:
+		t.Fatalf("tx %v is confirmed by a single follower", m.ID)
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+type staticSnapshot struct {
+	block int64
+	data  []byte
+}
+
+func (s staticSnapshot) LatestSnapshot() (int64, []byte, bool) {
+	return s.block, s.data, s.data != nil
+}
+
+func TestFetchSnapshotPicksLatest(t *testing.T) {
+	follower, _ := New("localhost", "8101")
+	p1, _ := New("localhost", "8102")
+	p2, _ := New("localhost", "8103")
+
+	// bigger than one chunk
+	latest := make([]byte, 3*snapshotChunkSize+1)
+	for i := range latest {
+		latest[i] = byte(i)
+	}
+	p1.SetSnapshotSource(staticSnapshot{block: 10, data: []byte("old")})
+	p2.SetSnapshotSource(staticSnapshot{block: 20, data: latest})
+	follower.UpdateHosts([]Peer{{Addr: "localhost:8102"}, {Addr: "localhost:8103"}})
+
+	go p1.Start()
+	go p2.Start()
+	defer p1.Stop()
+	defer p2.Stop()
+
+	require.Eventually(t, func() bool {
+		block, data, err := follower.FetchSnapshot(context.Background())
+		return err == nil && block == 20 && bytes.Equal(latest, data)
+	}, time.Second, 50*time.Millisecond)
+}
+
+func TestFetchPendingTxsMergesPeers(t *testing.T) {
+	leader, _ := New("localhost", "8104")
+	p1, _ := New("localhost", "8105")
+	p2, _ := New("localhost", "8106")
+
+	p1.SetTxStore(newMemStore(
+		Transaction{ID: "tx-1", TxData: []byte("hello-1")},
+		Transaction{ID: "tx-2", TxData: []byte("hello-2")},
+	))
+	p2.SetTxStore(newMemStore(
+		Transaction{ID: "tx-2", TxData: []byte("hello-2")},
+		Transaction{ID: "tx-3", TxData: []byte("hello-3")},
+	))
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8105"}, {Addr: "localhost:8106"}})
+
+	go p1.Start()
+	go p2.Start()
+	defer p1.Stop()
+	defer p2.Stop()
+
+	var txs []Transaction
+	require.Eventually(t, func() bool {
+		var err error
+		txs, err = leader.FetchPendingTxs(context.Background())
+		return err == nil && len(txs) == 3
+	}, time.Second, 50*time.Millisecond)
+
+	slices.SortFunc(txs, func(a, b Transaction) int { return strings.Compare(a.ID, b.ID) })
+	require.Equal(t, []Transaction{
+		{ID: "tx-1", TxData: []byte("hello-1")},
+		{ID: "tx-2", TxData: []byte("hello-2")},
+		{ID: "tx-3", TxData: []byte("hello-3")},
+	}, txs)
+}
+
+// remembers the order of stored txs, every failEvery-th store fails
+type orderStore struct {
+	memStore
+	failEvery int
+	calls     int
+	order     []string
+}
+
+func (s *orderStore) StoreTx(tx Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.calls%s.failEvery == 0 {
+		return errors.New("disk hiccup")
+	}
+	s.txs[tx.ID] = tx.TxData
+	s.order = append(s.order, tx.ID)
+	return nil
+}
+
+func TestReplicationKeepsOrderAcrossReconnects(t *testing.T) {
+	leader, distributedCh := New("localhost", "8107", WithQuorum(AckCount(1)))
+	follower, _ := New("localhost", "8108")
+	store := &orderStore{memStore: memStore{txs: make(map[string][]byte)}, failEvery: 97}
+	follower.SetTxStore(store)
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8108"}})
+
+	go follower.Start()
+	go leader.Start()
+	defer follower.Stop()
+	defer leader.Stop()
+
+	var expected []string
+	for i := range 500 {
+		tx := Transaction{ID: fmt.Sprintf("tx-%03d", i), TxData: []byte{byte(i)}}
+		expected = append(expected, tx.ID)
+		leader.DistributeTx(tx)
+	}
+
+	var distributed []string
+	timeout := time.After(10 * time.Second)
+	for len(distributed) < len(expected) {
+		select {
+		case m := <-distributedCh:
+			distributed = append(distributed, m.ID)
+		case <-timeout:
+			t.Fatalf("only %d of %d txs are distributed", len(distributed), len(expected))
+		}
+	}
+	require.Equal(t, expected, distributed)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.Equal(t, expected, store.order)
+}
+
+// rejects the txs with the given ids
+type rejectingStore struct {
+	memStore
+	rejected map[string]bool
+}
+
+func (s *rejectingStore) StoreTx(tx Transaction) error {
+	if s.rejected[tx.ID] {
+		return fmt.Errorf("%w: unknown encoding version", ErrBadTx)
+	}
+	return s.memStore.StoreTx(tx)
+}
+
+func TestRejectedTxIsSkipped(t *testing.T) {
+	leader, distributedCh := New("localhost", "8131", WithQuorum(AckCount(1)))
+	follower, _ := New("localhost", "8132")
+	store := &rejectingStore{memStore: memStore{txs: make(map[string][]byte)}, rejected: map[string]bool{"tx-0": true, "tx-2": true}}
+	follower.SetTxStore(store)
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8132"}})
+
+	go follower.Start()
+	go leader.Start()
+	defer follower.Stop()
+	defer leader.Stop()
+
+	for i := range 4 {
+		require.NoError(t, leader.DistributeTx(Transaction{ID: fmt.Sprintf("tx-%d", i)}))
+	}
+
+	var distributed []string
+	timeout := time.After(5 * time.Second)
+	for len(distributed) < 2 {
+		select {
+		case m := <-distributedCh:
+			distributed = append(distributed, m.ID)
+		case <-timeout:
+			t.Fatalf("only %v are distributed", distributed)
+		}
+	}
+	require.Equal(t, []string{"tx-1", "tx-3"}, distributed)
+	require.Len(t, store.GetTxs([]string{"tx-0", "tx-1", "tx-2", "tx-3"}), 2)
+}
+
+func TestSlowPeerDoesNotStallOthers(t *testing.T) {
+	leader, distributedCh := New("localhost", "8109", WithQuorum(AckCount(1)), WithQueue(2, OverflowReject))
+	follower, _ := New("localhost", "8110")
+	follower.SetTxStore(&memStore{txs: make(map[string][]byte)})
+	// nobody listens there
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8110"}, {Addr: "localhost:8111"}})
+
+	go follower.Start()
+	go leader.Start()
+	defer follower.Stop()
+	defer leader.Stop()
+
+	for i := range 10 {
+		tx := Transaction{ID: fmt.Sprintf("tx-%d", i)}
+		require.NoError(t, leader.DistributeTx(tx))
+		select {
+		case m := <-distributedCh:
+			require.Equal(t, tx.ID, m.ID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("tx %v is not distributed", tx.ID)
+		}
+	}
+}
+
+func TestFullQueueOverflow(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		overflow OverflowPolicy
+		// ids left in the queue
+		queued []string
+		err    error
+	}{
+		{name: "reject", overflow: OverflowReject, queued: []string{"tx-0", "tx-1"}, err: ErrQueueFull},
+		{name: "drop oldest", overflow: OverflowDropOldest, queued: []string{"tx-1", "tx-2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tr, _ := New("localhost", "8112", WithQuorum(AckCount(1)), WithQueue(2, tc.overflow))
+			s := tr.(*serv)
+			tr.UpdateHosts([]Peer{{Addr: "localhost:8113"}})
+			defer s.clients["localhost:8113"].replica.stop()
+
+			require.NoError(t, tr.DistributeTx(Transaction{ID: "tx-0"}))
+			require.NoError(t, tr.DistributeTx(Transaction{ID: "tx-1"}))
+			err := tr.DistributeTx(Transaction{ID: "tx-2"})
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			r := s.clients["localhost:8113"].replica
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			var queued []string
+			for _, p := range r.pending {
+				queued = append(queued, p.tx.ID)
+			}
+			require.Equal(t, tc.queued, queued)
+		})
+	}
+}
+
+func TestBlockedDistributionIsReleasedByStop(t *testing.T) {
+	tr, _ := New("localhost", "8114", WithQuorum(AckCount(1)), WithQueue(1, OverflowBlock))
+	s := tr.(*serv)
+	tr.UpdateHosts([]Peer{{Addr: "localhost:8115"}})
+
+	require.NoError(t, tr.DistributeTx(Transaction{ID: "tx-0"}))
+	errCh := make(chan error)
+	go func() {
+		errCh <- tr.DistributeTx(Transaction{ID: "tx-1"})
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("distribution isn't blocked: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.clients["localhost:8115"].replica.stop()
+	require.ErrorIs(t, <-errCh, ErrQueueFull)
+}
+
+func TestHintsAreReplayedWhenPeerIsBack(t *testing.T) {
+	leader, distributedCh := New("localhost", "8116", WithQuorum(AckCount(1)), WithHintedHandoff(200*time.Millisecond, 100))
+	go func() {
+		for range distributedCh {
+		}
+	}()
+	follower, _ := New("localhost", "8117")
+	store := newMemStore()
+	follower.SetTxStore(store)
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8117"}})
+	go leader.Start()
+	defer leader.Stop()
+
+	// queued, the peer could be back any moment
+	require.NoError(t, leader.DistributeTx(Transaction{ID: "tx-0"}))
+	time.Sleep(300 * time.Millisecond)
+	// the peer is down for too long, it's a hint now
+	require.ErrorIs(t, leader.DistributeTx(Transaction{ID: "tx-1"}), ErrQueueFull)
+
+	go follower.Start()
+	defer follower.Stop()
+
+	require.Eventually(t, func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return len(store.txs) == 2
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestReplayedHintsAreNotCountedOrDropped(t *testing.T) {
+	tr, distributedCh := New("localhost", "8133", WithQuorum(AckCount(1)), WithQueue(2, OverflowDropOldest), WithHintedHandoff(time.Hour, 100))
+	s := tr.(*serv)
+	// nobody listens there
+	tr.UpdateHosts([]Peer{{Addr: "localhost:8134"}})
+	r := s.clients["localhost:8134"].replica
+	defer r.stop()
+
+	r.mu.Lock()
+	for i := range 3 {
+		r.hints = append(r.hints, pendingTx{tx: Transaction{ID: fmt.Sprintf("hint-%d", i)}})
+	}
+	r.mu.Unlock()
+	r.up()
+	for i := range 3 {
+		require.NoError(t, tr.DistributeTx(Transaction{ID: fmt.Sprintf("tx-%d", i)}))
+	}
+
+	r.mu.Lock()
+	var queued []string
+	for _, p := range r.pending {
+		queued = append(queued, p.tx.ID)
+	}
+	r.mu.Unlock()
+	require.Equal(t, []string{"hint-0", "hint-1", "hint-2", "tx-1", "tx-2"}, queued)
+
+	// tx-0 is dropped, seqs aren't contiguous anymore
+	require.Len(t, r.nextBatch(0).Txs, 3)
+	batch := r.nextBatch(3)
+	require.Equal(t, uint64(5), batch.FirstSeq)
+	require.Len(t, batch.Txs, 2)
+
+	go r.ack(6)
+	for _, id := range []string{"tx-1", "tx-2"} {
+		select {
+		case m := <-distributedCh:
+			require.Equal(t, id, m.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("tx %v is not distributed", id)
+		}
+	}
+	select {
+	case m := <-distributedCh:
+		t.Fatalf("tx %v is distributed twice or is a hint", m.ID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPeerHealth(t *testing.T) {
+	leader, distributedCh := New("localhost", "8122", WithQuorum(AckCount(1)))
+	follower, _ := New("localhost", "8123")
+	follower.SetTxStore(newMemStore())
+	// nobody listens there
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8123", Region: "eu"}, {Addr: "localhost:8124"}})
+
+	go follower.Start()
+	go leader.Start()
+	defer follower.Stop()
+	defer leader.Stop()
+
+	for i := range 5 {
+		require.NoError(t, leader.DistributeTx(Transaction{ID: fmt.Sprintf("tx-%d", i)}))
+		<-distributedCh
+	}
+
+	require.Eventually(t, func() bool {
+		peers := leader.Peers()
+		return !peers[1].Healthy
+	}, 5*time.Second, 50*time.Millisecond)
+	peers := leader.Peers()
+	require.Len(t, peers, 2)
+	require.Equal(t, Peer{Addr: "localhost:8123", Region: "eu"}, peers[0].Peer)
+	require.True(t, peers[0].Healthy)
+	require.False(t, peers[0].LastAck.IsZero())
+	require.Positive(t, peers[0].RTT)
+	require.True(t, peers[1].LastAck.IsZero())
+
+	// the application hears about the dead one
+	timeout := time.After(5 * time.Second)
+	for down := false; !down; {
+		select {
+		case ev := <-leader.PeerEvents():
+			down = ev.Addr == "localhost:8124" && !ev.Healthy
+		case <-timeout:
+			t.Fatal("no event about the dead peer")
+		}
+	}
+
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8124"}})
+	for removed := false; !removed; {
+		select {
+		case ev := <-leader.PeerEvents():
+			removed = ev.Addr == "localhost:8123" && ev.ConnState == connectivity.Shutdown
+		case <-timeout:
+			t.Fatal("no event about the removed peer")
+		}
+	}
+}
+
+// StoreTx waits until released
+type stuckStore struct {
+	memStore
+	releaseCh chan struct{}
+}
+
+func (s *stuckStore) StoreTx(tx Transaction) error {
+	<-s.releaseCh
+	return s.memStore.StoreTx(tx)
+}
+
+func TestSpeculativeSendWhenAckIsLate(t *testing.T) {
+	leader, distributedCh := New("localhost", "8125",
+		WithQuorum(AckCount(1)),
+		WithReplicaSelector(FastestK(1)),
+		WithSpeculativeSend(50*time.Millisecond),
+	)
+	slow, _ := New("localhost", "8126")
+	fast, _ := New("localhost", "8127")
+	slowStore := &stuckStore{memStore: memStore{txs: make(map[string][]byte)}, releaseCh: make(chan struct{})}
+	fastStore := newMemStore()
+	slow.SetTxStore(slowStore)
+	fast.SetTxStore(fastStore)
+	// nothing is measured yet, so the first one by address is chosen
+	leader.UpdateHosts([]Peer{{Addr: "localhost:8126"}, {Addr: "localhost:8127"}})
+
+	go slow.Start()
+	go fast.Start()
+	go leader.Start()
+	defer slow.Stop()
+	defer close(slowStore.releaseCh)
+	defer fast.Stop()
+	defer leader.Stop()
+
+	before := speculativeSends.Value()
+	require.NoError(t, leader.DistributeTx(Transaction{ID: "tx-1", TxData: []byte("hello-1")}))
+	select {
+	case m := <-distributedCh:
+		require.Equal(t, "tx-1", m.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("tx is not distributed")
+	}
+	require.Equal(t, before+1, speculativeSends.Value())
+	require.Equal(t, []Transaction{{ID: "tx-1", TxData: []byte("hello-1")}}, fastStore.GetTxs([]string{"tx-1"}))
+}
@@ -0,0 +1,97 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	maroonv1 "github.com/akantsevoi/test-environment/gen/proto/maroon/p2p/v1"
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// snapshots are sent in pieces, so big ones fit into grpc message limits
+const snapshotChunkSize = 64 << 10
+
+func (s *serv) SetSnapshotSource(source SnapshotSource) {
+	s.snapshots = source
+}
+
+func (s *serv) GetSnapshot(_ *maroonv1.GetSnapshotRequest, stream grpc.ServerStreamingServer[maroonv1.SnapshotChunk]) error {
+	if s.snapshots == nil {
+		return status.Error(codes.Unavailable, "node doesn't take snapshots")
+	}
+	block, data, ok := s.snapshots.LatestSnapshot()
+	if !ok {
+		return status.Error(codes.NotFound, "no snapshot yet")
+	}
+
+	for len(data) > 0 {
+		n := min(len(data), snapshotChunkSize)
+		if err := stream.Send(&maroonv1.SnapshotChunk{BlockNumber: block, Data: data[:n]}); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+func (s *serv) FetchSnapshot(ctx context.Context) (int64, []byte, error) {
+	s.clientsMu.RLock()
+	peers := make(map[string]maroonv1.P2PServiceClient, len(s.clients))
+	for host, hostI := range s.clients {
+		peers[host] = hostI.client
+	}
+	s.clientsMu.RUnlock()
+
+	var bestBlock int64 = -1
+	var best []byte
+	var errs []error
+	for host, client := range peers {
+		block, data, err := fetchSnapshot(ctx, client)
+		if err != nil {
+			logger.Warningf(logger.Network, "failed to get snapshot from %v: %v", host, err)
+			errs = append(errs, err)
+			continue
+		}
+		if block > bestBlock {
+			bestBlock, best = block, data
+		}
+	}
+
+	if best == nil {
+		return 0, nil, fmt.Errorf("no snapshot on peers: %w", errors.Join(errs...))
+	}
+	return bestBlock, best, nil
+}
+
+func fetchSnapshot(ctx context.Context, client maroonv1.P2PServiceClient) (int64, []byte, error) {
+	stream, err := client.GetSnapshot(ctx, &maroonv1.GetSnapshotRequest{})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var block int64 = -1
+	var data []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if block >= 0 && chunk.BlockNumber != block {
+			return 0, nil, fmt.Errorf("snapshot changed in the middle: %d -> %d", block, chunk.BlockNumber)
+		}
+		block = chunk.BlockNumber
+		data = append(data, chunk.Data...)
+	}
+	if block < 0 {
+		return 0, nil, errors.New("empty snapshot")
+	}
+	return block, data, nil
+}
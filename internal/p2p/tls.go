@@ -0,0 +1,173 @@
+package p2p
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+// mTLS between the peers, all files are PEM
+// they are read again once they change, so certificates can be rotated without a restart
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// CA that signs the certificates of all the peers
+	CAFile string
+}
+
+// keeps the last loaded certificate and CA
+type certReloader struct {
+	files TLSFiles
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	pool     *x509.CertPool
+	modTimes [3]time.Time
+}
+
+func newCertReloader(files TLSFiles) *certReloader {
+	return &certReloader{files: files}
+}
+
+// reloads the files if any of them has changed
+// if they can't be loaded, the previous ones are used
+func (r *certReloader) get() (*tls.Certificate, *x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var modTimes [3]time.Time
+	for i, name := range []string{r.files.CertFile, r.files.KeyFile, r.files.CAFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return r.loaded(err)
+		}
+		modTimes[i] = info.ModTime()
+	}
+	if r.cert != nil && modTimes == r.modTimes {
+		return r.cert, r.pool, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.files.CertFile, r.files.KeyFile)
+	if err != nil {
+		return r.loaded(err)
+	}
+	caPEM, err := os.ReadFile(r.files.CAFile)
+	if err != nil {
+		return r.loaded(err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return r.loaded(fmt.Errorf("no certificates in %v", r.files.CAFile))
+	}
+
+	if r.cert != nil {
+		logger.Infof(logger.Network, "tls certificates are reloaded")
+	}
+	r.cert, r.pool, r.modTimes = &cert, pool, modTimes
+	return r.cert, r.pool, nil
+}
+
+// should be called under mu
+func (r *certReloader) loaded(err error) (*tls.Certificate, *x509.CertPool, error) {
+	if r.cert == nil {
+		return nil, nil, fmt.Errorf("failed to load tls certificates: %w", err)
+	}
+	// files can be in the middle of a rotation
+	logger.Warningf(logger.Network, "failed to reload tls certificates, previous ones are used: %v", err)
+	return r.cert, r.pool, nil
+}
+
+// accepts only the peers from the host list
+func (s *serv) serverTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		// the chain is verified in VerifyConnection, CA can be reloaded
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _, err := s.tls.get()
+			return cert, err
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool, err := s.tls.get()
+			if err != nil {
+				return err
+			}
+			leaf, err := verifyChain(cs.PeerCertificates, pool, x509.ExtKeyUsageClientAuth)
+			if err != nil {
+				return err
+			}
+			for _, name := range s.peerNames() {
+				if leaf.VerifyHostname(name) == nil {
+					return nil
+				}
+			}
+			return fmt.Errorf("certificate %q doesn't belong to any peer", leaf.Subject.CommonName)
+		},
+	}
+}
+
+// the peer has to have a certificate for the host it's dialed by
+func (s *serv) clientTLSConfig(host string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		// the chain is verified in VerifyConnection, CA can be reloaded
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _, err := s.tls.get()
+			return cert, err
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			_, pool, err := s.tls.get()
+			if err != nil {
+				return err
+			}
+			leaf, err := verifyChain(cs.PeerCertificates, pool, x509.ExtKeyUsageServerAuth)
+			if err != nil {
+				return err
+			}
+			return leaf.VerifyHostname(host)
+		},
+	}
+}
+
+// hostnames of the configured peers
+func (s *serv) peerNames() []string {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	names := make([]string, 0, len(s.clients))
+	for addr := range s.clients {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		names = append(names, host)
+	}
+	return names
+}
+
+// returns the leaf certificate if it's signed by one of the roots
+func verifyChain(certs []*x509.Certificate, roots *x509.CertPool, usage x509.ExtKeyUsage) (*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("peer has no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certs[0], nil
+}
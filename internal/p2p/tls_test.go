@@ -0,0 +1,171 @@
+package p2p
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// issues certificates for the nodes of a test, files are in a temp dir
+type testCA struct {
+	t      *testing.T
+	dir    string
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	caFile string
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	writePEM(t, caFile, "CERTIFICATE", der)
+	return &testCA{t: t, dir: dir, cert: cert, key: key, caFile: caFile, serial: 1}
+}
+
+// certificate of the node for both server and client side, the files are overwritten on every call
+func (ca *testCA) issue(node string, dnsNames ...string) TLSFiles {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(ca.t, err)
+	ca.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: node},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(ca.t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(ca.t, err)
+
+	files := TLSFiles{
+		CertFile: filepath.Join(ca.dir, node+".crt"),
+		KeyFile:  filepath.Join(ca.dir, node+".key"),
+		CAFile:   ca.caFile,
+	}
+	writePEM(ca.t, files.CertFile, "CERTIFICATE", der)
+	writePEM(ca.t, files.KeyFile, "EC PRIVATE KEY", keyDER)
+	return files
+}
+
+func writePEM(t *testing.T, name, typ string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(name, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
+
+func TestTLSPeersCommunicate(t *testing.T) {
+	ca := newTestCA(t)
+	a, _ := New("localhost", "8118", WithTLS(ca.issue("a", "localhost")))
+	b, _ := New("localhost", "8119", WithTLS(ca.issue("b", "localhost")))
+	b.SetTxStore(newMemStore(Transaction{ID: "tx-1", TxData: []byte("hello-1")}))
+	a.UpdateHosts([]Peer{{Addr: "localhost:8119"}})
+	b.UpdateHosts([]Peer{{Addr: "localhost:8118"}})
+
+	go a.Start()
+	go b.Start()
+	defer a.Stop()
+	defer b.Stop()
+
+	require.Eventually(t, func() bool {
+		txs, err := a.FetchTxs(context.Background(), []string{"tx-1"})
+		return err == nil && len(txs) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestTLSRejectsUnknownPeers(t *testing.T) {
+	ca := newTestCA(t)
+	server, _ := New("localhost", "8120", WithTLS(ca.issue("server", "localhost")))
+	server.SetTxStore(newMemStore(Transaction{ID: "tx-1", TxData: []byte("hello-1")}))
+	server.UpdateHosts([]Peer{{Addr: "localhost:8121"}})
+	go server.Start()
+	defer server.Stop()
+
+	for name, files := range map[string]TLSFiles{
+		"not a peer":  ca.issue("intruder", "intruder.example"),
+		"another ca":  newTestCA(t).issue("stranger", "localhost"),
+		"no tls used": {},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var opts []Option
+			if files.CertFile != "" {
+				opts = append(opts, WithTLS(files))
+			}
+			client, _ := New("localhost", "8121", opts...)
+			client.UpdateHosts([]Peer{{Addr: "localhost:8120"}})
+			defer client.UpdateHosts(nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			_, err := client.FetchTxs(ctx, []string{"tx-1"})
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCertificatesAreReloaded(t *testing.T) {
+	ca := newTestCA(t)
+	files := ca.issue("node", "localhost")
+	r := newCertReloader(files)
+	first, _, err := r.get()
+	require.NoError(t, err)
+
+	// rotation in progress, the key doesn't match the certificate yet
+	key, err := os.ReadFile(files.KeyFile)
+	require.NoError(t, err)
+	ca.issue("node", "localhost")
+	require.NoError(t, os.WriteFile(files.KeyFile, key, 0o600))
+	touch(t, files.CertFile, files.KeyFile)
+	cert, _, err := r.get()
+	require.NoError(t, err)
+	require.Equal(t, first, cert)
+
+	ca.issue("node", "localhost")
+	touch(t, files.CertFile, files.KeyFile)
+	cert, _, err = r.get()
+	require.NoError(t, err)
+	require.NotEqual(t, first.Certificate, cert.Certificate)
+}
+
+// file systems with coarse timestamps can leave mtime the same
+func touch(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		mtime := info.ModTime().Add(time.Second)
+		require.NoError(t, os.Chtimes(name, mtime, mtime))
+	}
+}
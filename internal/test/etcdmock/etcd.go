@@ -1,11 +1,17 @@
 package etcdmock
 
 import (
+	"bytes"
 	"context"
-	"strings"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
 
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
 )
 
 ///
@@ -13,50 +19,458 @@ import (
 /// Keep in mind that it's no accurate and reliable implementation but rather test helper
 ///
 
+var ErrLeaseNotFound = errors.New("etcdmock: requested lease not found")
+
 func New() ETCDMock {
 	return &etcd{
-		prefixWatch: make(map[string]chan clientv3.WatchResponse),
-		store:       make(map[string]string),
+		store:   make(map[string]*mvccpb.KeyValue),
+		history: make(map[string][]*mvccpb.KeyValue),
+		leases:  make(map[clientv3.LeaseID]bool),
 	}
 }
 
 type ETCDMock interface {
 	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error)
+	Txn(ctx context.Context) clientv3.Txn
 	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error)
+	Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error)
+
+	// test helper: imitates lease expiration, all the attached keys are deleted
+	ExpireLease(id clientv3.LeaseID)
 }
 
 type etcd struct {
-	prefixWatch map[string](chan clientv3.WatchResponse)
-	store       map[string]string
+	mu sync.Mutex
+
+	rev   int64
+	store map[string]*mvccpb.KeyValue
+	// every version of a key, deletions have zero version
+	// Get with WithRev reads from it, nothing is compacted
+	history   map[string][]*mvccpb.KeyValue
+	leases    map[clientv3.LeaseID]bool
+	lastLease clientv3.LeaseID
+	watchers  []*watcher
+}
+
+type watcher struct {
+	key, end []byte
+	ch       chan clientv3.WatchResponse
+
+	// events are queued and sent by a separate goroutine,
+	// a watcher that stops reading must not block the mock
+	mu     sync.Mutex
+	queue  []clientv3.WatchResponse
+	wakeCh chan struct{}
 }
 
 func (e *etcd) Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error) {
-	e.store[key] = val
-
-	// Notify all watchers
-	for prefix, watchCh := range e.prefixWatch {
-		if strings.HasPrefix(key, prefix) {
-			watchCh <- clientv3.WatchResponse{
-				Events: []*clientv3.Event{
-					{
-						Type: clientv3.EventTypePut,
-						Kv: &mvccpb.KeyValue{
-							Key:   []byte(key),
-							Value: []byte(val),
-						},
-					},
-				},
-			}
-		}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	op := clientv3.OpPut(key, val, opts...)
+	if err := e.checkLease(op); err != nil {
+		return nil, err
 	}
+	e.rev++
+	e.put(op)
+	return &clientv3.PutResponse{Header: e.header()}, nil
+}
+
+func (e *etcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.get(clientv3.OpGet(key, opts...)), nil
+}
+
+func (e *etcd) Delete(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 
-	// TODO: implement to mimick better?
-	return &clientv3.PutResponse{}, nil
+	e.rev++
+	return e.delete(clientv3.OpDelete(key, opts...)), nil
 }
 
 // TODO: only support by prefix right now
 func (e *etcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
-	newWatchCh := make(clientv3.WatchChan)
-	e.prefixWatch[key] = make(chan clientv3.WatchResponse)
-	return newWatchCh
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	op := clientv3.OpGet(key, opts...)
+	w := &watcher{
+		key:    op.KeyBytes(),
+		end:    op.RangeBytes(),
+		ch:     make(chan clientv3.WatchResponse, 100),
+		wakeCh: make(chan struct{}, 1),
+	}
+	e.watchers = append(e.watchers, w)
+	go e.forward(ctx, w)
+	return w.ch
+}
+
+func (e *etcd) forward(ctx context.Context, w *watcher) {
+	defer e.unwatch(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wakeCh:
+		}
+
+		w.mu.Lock()
+		queue := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, resp := range queue {
+			select {
+			case <-ctx.Done():
+				return
+			case w.ch <- resp:
+			}
+		}
+	}
+}
+
+func (e *etcd) unwatch(w *watcher) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.watchers = slices.DeleteFunc(e.watchers, func(x *watcher) bool { return x == w })
+}
+
+func (e *etcd) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastLease++
+	e.leases[e.lastLease] = true
+	return &clientv3.LeaseGrantResponse{ID: e.lastLease, TTL: ttl}, nil
+}
+
+func (e *etcd) KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.leases[id] {
+		return nil, ErrLeaseNotFound
+	}
+	return &clientv3.LeaseKeepAliveResponse{ID: id}, nil
+}
+
+func (e *etcd) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	e.ExpireLease(id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+func (e *etcd) ExpireLease(id clientv3.LeaseID) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.leases, id)
+	e.rev++
+	for key, kv := range e.store {
+		if clientv3.LeaseID(kv.Lease) == id {
+			e.delete(clientv3.OpDelete(key))
+		}
+	}
+}
+
+func (e *etcd) Txn(ctx context.Context) clientv3.Txn {
+	return &txn{e: e}
+}
+
+type txn struct {
+	e         *etcd
+	cmps      []clientv3.Cmp
+	then, els []clientv3.Op
+}
+
+func (t *txn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *txn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.then = append(t.then, ops...)
+	return t
+}
+
+func (t *txn) Else(ops ...clientv3.Op) clientv3.Txn {
+	t.els = append(t.els, ops...)
+	return t
+}
+
+func (t *txn) Commit() (*clientv3.TxnResponse, error) {
+	e := t.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	succeeded := true
+	for _, cmp := range t.cmps {
+		if !e.compare(cmp) {
+			succeeded = false
+			break
+		}
+	}
+	ops := t.then
+	if !succeeded {
+		ops = t.els
+	}
+
+	for _, op := range ops {
+		if err := e.checkLease(op); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &clientv3.TxnResponse{Succeeded: succeeded}
+	written := false
+	for _, op := range ops {
+		if (op.IsPut() || op.IsDelete()) && !written {
+			e.rev++
+			written = true
+		}
+		switch {
+		case op.IsPut():
+			e.put(op)
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponsePut{ResponsePut: &pb.PutResponse{}}})
+		case op.IsGet():
+			r := e.get(op)
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseRange{ResponseRange: (*pb.RangeResponse)(r)}})
+		case op.IsDelete():
+			r := e.delete(op)
+			resp.Responses = append(resp.Responses, &pb.ResponseOp{Response: &pb.ResponseOp_ResponseDeleteRange{ResponseDeleteRange: (*pb.DeleteRangeResponse)(r)}})
+		}
+	}
+	resp.Header = e.header()
+	return resp, nil
+}
+
+// should be called under mu
+
+func (e *etcd) header() *pb.ResponseHeader {
+	return &pb.ResponseHeader{Revision: e.rev}
+}
+
+func leaseOf(op clientv3.Op) clientv3.LeaseID {
+	return clientv3.LeaseID(capture(op).put.Lease)
+}
+
+func (e *etcd) checkLease(op clientv3.Op) error {
+	if !op.IsPut() {
+		return nil
+	}
+	if lease := leaseOf(op); lease != clientv3.NoLease && !e.leases[lease] {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+func (e *etcd) put(op clientv3.Op) {
+	key := string(op.KeyBytes())
+	kv, ok := e.store[key]
+	if !ok {
+		kv = &mvccpb.KeyValue{Key: op.KeyBytes(), CreateRevision: e.rev}
+		e.store[key] = kv
+	}
+	kv.Value = op.ValueBytes()
+	kv.ModRevision = e.rev
+	kv.Version++
+	kv.Lease = int64(leaseOf(op))
+	e.history[key] = append(e.history[key], copyKV(kv))
+
+	e.notify(&clientv3.Event{Type: clientv3.EventTypePut, Kv: copyKV(kv)})
+}
+
+func (e *etcd) delete(op clientv3.Op) *clientv3.DeleteResponse {
+	resp := &clientv3.DeleteResponse{Header: e.header()}
+	for _, key := range e.keys(op.KeyBytes(), op.RangeBytes()) {
+		kv := e.store[key]
+		delete(e.store, key)
+		e.history[key] = append(e.history[key], &mvccpb.KeyValue{Key: kv.Key, ModRevision: e.rev})
+		resp.Deleted++
+		e.notify(&clientv3.Event{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: kv.Key, ModRevision: e.rev}})
+	}
+	return resp
+}
+
+func (e *etcd) get(op clientv3.Op) *clientv3.GetResponse {
+	resp := &clientv3.GetResponse{Header: e.header()}
+	store := e.store
+	if op.Rev() > 0 {
+		store = e.storeAt(op.Rev())
+	}
+	keys := keysOf(store, op.KeyBytes(), op.RangeBytes())
+	resp.Count = int64(len(keys))
+	req := capture(op).rng
+	// only sorting by key is supported, ascending is the default order anyway
+	if req.SortTarget == pb.RangeRequest_KEY && req.SortOrder == pb.RangeRequest_DESCEND {
+		slices.Reverse(keys)
+	}
+	if limit := req.Limit; limit > 0 && int64(len(keys)) > limit {
+		keys = keys[:limit]
+		resp.More = true
+	}
+	for _, key := range keys {
+		kv := copyKV(store[key])
+		if op.IsKeysOnly() {
+			kv.Value = nil
+		}
+		resp.Kvs = append(resp.Kvs, kv)
+	}
+	return resp
+}
+
+// sorted keys in [key, end), only the key itself if end is empty
+func (e *etcd) keys(key, end []byte) []string {
+	return keysOf(e.store, key, end)
+}
+
+func keysOf(store map[string]*mvccpb.KeyValue, key, end []byte) []string {
+	var res []string
+	for k := range store {
+		if inRange([]byte(k), key, end) {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// what the store was at the revision
+func (e *etcd) storeAt(rev int64) map[string]*mvccpb.KeyValue {
+	res := make(map[string]*mvccpb.KeyValue)
+	for key, versions := range e.history {
+		var last *mvccpb.KeyValue
+		for _, kv := range versions {
+			if kv.ModRevision > rev {
+				break
+			}
+			last = kv
+		}
+		if last != nil && last.Version > 0 {
+			res[key] = last
+		}
+	}
+	return res
+}
+
+func inRange(k, key, end []byte) bool {
+	if len(end) == 0 {
+		return bytes.Equal(k, key)
+	}
+	// "\x00" means everything from the key
+	return bytes.Compare(k, key) >= 0 && (bytes.Equal(end, []byte{0}) || bytes.Compare(k, end) < 0)
+}
+
+func (e *etcd) compare(cmp clientv3.Cmp) bool {
+	kv, ok := e.store[string(cmp.KeyBytes())]
+	if !ok {
+		// absent key has zero version and revisions
+		kv = &mvccpb.KeyValue{}
+	}
+
+	var res int
+	switch cmp.Target {
+	case pb.Compare_VERSION:
+		res = compareInt(kv.Version, cmp.TargetUnion.(*pb.Compare_Version).Version)
+	case pb.Compare_CREATE:
+		res = compareInt(kv.CreateRevision, cmp.TargetUnion.(*pb.Compare_CreateRevision).CreateRevision)
+	case pb.Compare_MOD:
+		res = compareInt(kv.ModRevision, cmp.TargetUnion.(*pb.Compare_ModRevision).ModRevision)
+	case pb.Compare_LEASE:
+		res = compareInt(kv.Lease, cmp.TargetUnion.(*pb.Compare_Lease).Lease)
+	case pb.Compare_VALUE:
+		if !ok {
+			return false
+		}
+		res = bytes.Compare(kv.Value, cmp.ValueBytes())
+	}
+
+	switch cmp.Result {
+	case pb.Compare_EQUAL:
+		return res == 0
+	case pb.Compare_NOT_EQUAL:
+		return res != 0
+	case pb.Compare_GREATER:
+		return res > 0
+	case pb.Compare_LESS:
+		return res < 0
+	}
+	return false
+}
+
+func compareInt(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// should be called under mu, doesn't block on the watchers
+func (e *etcd) notify(ev *clientv3.Event) {
+	for _, w := range e.watchers {
+		if !inRange(ev.Kv.Key, w.key, w.end) {
+			continue
+		}
+		w.mu.Lock()
+		w.queue = append(w.queue, clientv3.WatchResponse{
+			Header: *e.header(),
+			Events: []*clientv3.Event{ev},
+		})
+		w.mu.Unlock()
+		select {
+		case w.wakeCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func copyKV(kv *mvccpb.KeyValue) *mvccpb.KeyValue {
+	c := *kv
+	return &c
+}
+
+// clientv3.Op doesn't expose the options (lease, limit, sort),
+// so the op goes through the real client that builds the request and the request is captured
+type recorder struct {
+	put *pb.PutRequest
+	rng *pb.RangeRequest
+}
+
+func capture(op clientv3.Op) *recorder {
+	r := &recorder{put: &pb.PutRequest{}, rng: &pb.RangeRequest{}}
+	_, _ = clientv3.NewKVFromKVClient(r, nil).Do(context.Background(), op)
+	return r
+}
+
+func (r *recorder) Range(ctx context.Context, in *pb.RangeRequest, opts ...grpc.CallOption) (*pb.RangeResponse, error) {
+	r.rng = in
+	return &pb.RangeResponse{}, nil
+}
+
+func (r *recorder) Put(ctx context.Context, in *pb.PutRequest, opts ...grpc.CallOption) (*pb.PutResponse, error) {
+	r.put = in
+	return &pb.PutResponse{}, nil
+}
+
+func (r *recorder) DeleteRange(ctx context.Context, in *pb.DeleteRangeRequest, opts ...grpc.CallOption) (*pb.DeleteRangeResponse, error) {
+	return &pb.DeleteRangeResponse{}, nil
+}
+
+func (r *recorder) Txn(ctx context.Context, in *pb.TxnRequest, opts ...grpc.CallOption) (*pb.TxnResponse, error) {
+	return &pb.TxnResponse{}, nil
+}
+
+func (r *recorder) Compact(ctx context.Context, in *pb.CompactionRequest, opts ...grpc.CallOption) (*pb.CompactionResponse, error) {
+	return &pb.CompactionResponse{}, nil
 }
@@ -12,6 +12,8 @@ type Leader struct {
 	leaderKey string
 	nodeID    string
 	lease     clientv3.LeaseID
+	// create revision of the leader key of the last successful campaign
+	revision int64
 }
 
 func NewLeader(cli *clientv3.Client, leaderKey, nodeID string) *Leader {
@@ -43,6 +45,8 @@ func (l *Leader) Campaign() (<-chan struct{}, error) {
 		currentLeader := string(resp.Responses[0].GetResponseRange().Kvs[0].Value)
 		return nil, fmt.Errorf("failed to become leader, current leader is: %s", currentLeader)
 	}
+	// the key is created by this transaction
+	l.revision = resp.Header.Revision
 
 	keepAliveCh, err := l.cli.KeepAlive(context.Background(), lease.ID)
 	if err != nil {
@@ -62,6 +66,20 @@ func (l *Leader) Campaign() (<-chan struct{}, error) {
 	return leaderCh, nil
 }
 
+// create revision of the leader key
+// leader writes compare against it, so they fail once the key is gone or recreated
+func (l *Leader) Revision() int64 {
+	return l.revision
+}
+
+// gives the leadership up, the channel from Campaign gets closed
+func (l *Leader) Resign() error {
+	if _, err := l.cli.Revoke(context.Background(), l.lease); err != nil {
+		return fmt.Errorf("failed to revoke lease: %v", err)
+	}
+	return nil
+}
+
 func (l *Leader) IsLeader() bool {
 	resp, err := l.cli.Get(context.Background(), l.leaderKey)
 	if err != nil {
@@ -0,0 +1,241 @@
+package keyrange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcd layout under the prefix:
+//   - next            - the lowest range index that was never given out
+//   - owner/<index>   - gateway that owns the range, attached to the gateway's lease
+//     so it disappears when the gateway is gone
+//   - reserved/<index> - offsets below this value could've been used by some owner
+//     gateway has to reserve offsets before using them, so the range can be reclaimed without collisions
+const (
+	nextKey        = "next"
+	ownerPrefix    = "owner/"
+	reservedPrefix = "reserved/"
+
+	// how many times to retry when other node changed the same keys
+	maxTxnAttempts = 10
+)
+
+var (
+	ErrNoRanges   = errors.New("no free ranges left")
+	ErrNotOwner   = errors.New("range is not owned by the gateway")
+	ErrContention = errors.New("too many concurrent allocations, try again")
+)
+
+type ETCD interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Txn(ctx context.Context) clientv3.Txn
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error)
+	Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error)
+}
+
+type Allocator struct {
+	cli    ETCD
+	prefix string
+}
+
+type owner struct {
+	GatewayID string `json:"gatewayID"`
+	Lease     int64  `json:"lease"`
+}
+
+func NewAllocator(cli ETCD, prefix string) *Allocator {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Allocator{
+		cli:    cli,
+		prefix: prefix,
+	}
+}
+
+// grants a lease for the gateway and gives it the first range
+// gateway has to keep the lease alive, otherwise its ranges are reclaimed
+func (a *Allocator) Acquire(ctx context.Context, gatewayID string, ttlSeconds int64) (clientv3.LeaseID, Range, error) {
+	lease, err := a.cli.Grant(ctx, ttlSeconds)
+	if err != nil {
+		return clientv3.NoLease, Range{}, fmt.Errorf("failed to grant lease: %w", err)
+	}
+
+	r, err := a.Next(ctx, gatewayID, lease.ID)
+	if err != nil {
+		if _, rErr := a.cli.Revoke(ctx, lease.ID); rErr != nil {
+			err = errors.Join(err, rErr)
+		}
+		return clientv3.NoLease, Range{}, err
+	}
+	return lease.ID, r, nil
+}
+
+// gives one more range to the gateway under its existing lease
+// ranges of expired gateways go first, then never used ones
+func (a *Allocator) Next(ctx context.Context, gatewayID string, lease clientv3.LeaseID) (Range, error) {
+	ownerRec, err := json.Marshal(owner{GatewayID: gatewayID, Lease: int64(lease)})
+	if err != nil {
+		return Range{}, err
+	}
+
+	for attempt := 0; attempt < maxTxnAttempts; attempt++ {
+		r, ok, err := a.reclaim(ctx, string(ownerRec), lease)
+		if err != nil {
+			return Range{}, err
+		}
+		if ok {
+			return r, nil
+		}
+
+		r, ok, err = a.allocateNew(ctx, string(ownerRec), lease)
+		if err != nil {
+			return Range{}, err
+		}
+		if ok {
+			return r, nil
+		}
+	}
+	return Range{}, ErrContention
+}
+
+func (a *Allocator) KeepAlive(ctx context.Context, lease clientv3.LeaseID) error {
+	_, err := a.cli.KeepAliveOnce(ctx, lease)
+	return err
+}
+
+// gateway promises not to use offsets >= upTo until the next reservation
+// fails with ErrNotOwner if the range was reclaimed
+func (a *Allocator) Reserve(ctx context.Context, gatewayID string, lease clientv3.LeaseID, index, upTo uint64) error {
+	if upTo > RangeSize {
+		upTo = RangeSize
+	}
+	ownerRec, err := json.Marshal(owner{GatewayID: gatewayID, Lease: int64(lease)})
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(a.ownerKey(index)), "=", string(ownerRec))).
+		Then(clientv3.OpPut(a.reservedKey(index), strconv.FormatUint(upTo, 10))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("failed to reserve offsets: %w", err)
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %d", ErrNotOwner, index)
+	}
+	return nil
+}
+
+// takes the lowest range that has no owner and is not used up
+func (a *Allocator) reclaim(ctx context.Context, ownerRec string, lease clientv3.LeaseID) (Range, bool, error) {
+	reserved, err := a.cli.Get(ctx, a.prefix+reservedPrefix, clientv3.WithPrefix())
+	if err != nil {
+		return Range{}, false, fmt.Errorf("failed to list ranges: %w", err)
+	}
+	owners, err := a.cli.Get(ctx, a.prefix+ownerPrefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return Range{}, false, fmt.Errorf("failed to list owners: %w", err)
+	}
+
+	owned := make(map[uint64]bool, len(owners.Kvs))
+	for _, kv := range owners.Kvs {
+		index, err := a.indexOf(string(kv.Key), ownerPrefix)
+		if err != nil {
+			return Range{}, false, err
+		}
+		owned[index] = true
+	}
+
+	// keys are sorted as strings, not as numbers, but any free range is good enough
+	for _, kv := range reserved.Kvs {
+		index, err := a.indexOf(string(kv.Key), reservedPrefix)
+		if err != nil {
+			return Range{}, false, err
+		}
+		if owned[index] {
+			continue
+		}
+		offset, err := strconv.ParseUint(string(kv.Value), 10, 64)
+		if err != nil {
+			return Range{}, false, fmt.Errorf("bad reserved offset of range %d: %w", index, err)
+		}
+		if offset >= RangeSize {
+			// used up
+			continue
+		}
+
+		resp, err := a.cli.Txn(ctx).
+			If(
+				clientv3.Compare(clientv3.Version(a.ownerKey(index)), "=", 0),
+				clientv3.Compare(clientv3.ModRevision(a.reservedKey(index)), "=", kv.ModRevision),
+			).
+			Then(clientv3.OpPut(a.ownerKey(index), ownerRec, clientv3.WithLease(lease))).
+			Commit()
+		if err != nil {
+			return Range{}, false, fmt.Errorf("failed to reclaim range %d: %w", index, err)
+		}
+		if resp.Succeeded {
+			return Range{Index: index, Offset: offset}, true, nil
+		}
+		// someone was faster, try next one
+	}
+	return Range{}, false, nil
+}
+
+func (a *Allocator) allocateNew(ctx context.Context, ownerRec string, lease clientv3.LeaseID) (Range, bool, error) {
+	resp, err := a.cli.Get(ctx, a.prefix+nextKey)
+	if err != nil {
+		return Range{}, false, fmt.Errorf("failed to get next range: %w", err)
+	}
+
+	var index uint64
+	var modRev int64
+	if len(resp.Kvs) > 0 {
+		modRev = resp.Kvs[0].ModRevision
+		index, err = strconv.ParseUint(string(resp.Kvs[0].Value), 10, 64)
+		if err != nil {
+			return Range{}, false, fmt.Errorf("bad next range index: %w", err)
+		}
+	}
+	if index > MaxRangeIndex {
+		return Range{}, false, ErrNoRanges
+	}
+
+	txnResp, err := a.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(a.prefix+nextKey), "=", modRev)).
+		Then(
+			clientv3.OpPut(a.prefix+nextKey, strconv.FormatUint(index+1, 10)),
+			clientv3.OpPut(a.ownerKey(index), ownerRec, clientv3.WithLease(lease)),
+			clientv3.OpPut(a.reservedKey(index), "0"),
+		).
+		Commit()
+	if err != nil {
+		return Range{}, false, fmt.Errorf("failed to allocate range %d: %w", index, err)
+	}
+	return Range{Index: index}, txnResp.Succeeded, nil
+}
+
+func (a *Allocator) ownerKey(index uint64) string {
+	return fmt.Sprintf("%s%s%d", a.prefix, ownerPrefix, index)
+}
+
+func (a *Allocator) reservedKey(index uint64) string {
+	return fmt.Sprintf("%s%s%d", a.prefix, reservedPrefix, index)
+}
+
+func (a *Allocator) indexOf(key, keyPrefix string) (uint64, error) {
+	index, err := strconv.ParseUint(strings.TrimPrefix(key, a.prefix+keyPrefix), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad range key %q: %w", key, err)
+	}
+	return index, nil
+}
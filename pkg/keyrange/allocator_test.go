@@ -0,0 +1,70 @@
+package keyrange
+
+import (
+	"context"
+	"testing"
+
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeForIndex(t *testing.T) {
+	first, last, err := RangeForIndex(2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2_147_483_648), first)
+	require.Equal(t, uint64(3_221_225_471), last)
+
+	_, last, err = RangeForIndex(MaxRangeIndex)
+	require.NoError(t, err)
+	require.Equal(t, ^uint64(0), last)
+
+	_, _, err = RangeForIndex(MaxRangeIndex + 1)
+	require.ErrorIs(t, err, ErrIndexOutOfRange)
+}
+
+func TestAllocatorGivesDistinctRanges(t *testing.T) {
+	ctx := context.Background()
+	alloc := NewAllocator(etcdmock.New(), "/maroon/ranges")
+
+	leaseA, rA, err := alloc.Acquire(ctx, "gw-a", 10)
+	require.NoError(t, err)
+	leaseB, rB, err := alloc.Acquire(ctx, "gw-b", 10)
+	require.NoError(t, err)
+	require.NotEqual(t, leaseA, leaseB)
+	require.Equal(t, Range{Index: 0}, rA)
+	require.Equal(t, Range{Index: 1}, rB)
+
+	// gateway A is close to the end of its range
+	rA2, err := alloc.Next(ctx, "gw-a", leaseA)
+	require.NoError(t, err)
+	require.Equal(t, Range{Index: 2}, rA2)
+}
+
+func TestAllocatorReclaimsExpiredRanges(t *testing.T) {
+	ctx := context.Background()
+	etcd := etcdmock.New()
+	alloc := NewAllocator(etcd, "/maroon/ranges")
+
+	leaseA, rA, err := alloc.Acquire(ctx, "gw-a", 10)
+	require.NoError(t, err)
+	rA2, err := alloc.Next(ctx, "gw-a", leaseA)
+	require.NoError(t, err)
+
+	require.NoError(t, alloc.Reserve(ctx, "gw-a", leaseA, rA.Index, 100))
+	// second range is used up
+	require.NoError(t, alloc.Reserve(ctx, "gw-a", leaseA, rA2.Index, RangeSize))
+
+	etcd.ExpireLease(leaseA)
+	require.Error(t, alloc.KeepAlive(ctx, leaseA))
+	require.ErrorIs(t, alloc.Reserve(ctx, "gw-a", leaseA, rA.Index, 200), ErrNotOwner)
+
+	// B continues after whatever A could've used
+	leaseB, rB, err := alloc.Acquire(ctx, "gw-b", 10)
+	require.NoError(t, err)
+	require.Equal(t, Range{Index: rA.Index, Offset: 100}, rB)
+
+	// used up range is never given again
+	rB2, err := alloc.Next(ctx, "gw-b", leaseB)
+	require.NoError(t, err)
+	require.Equal(t, Range{Index: 2}, rB2)
+}
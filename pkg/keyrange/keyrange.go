@@ -0,0 +1,46 @@
+package keyrange
+
+import (
+	"errors"
+	"fmt"
+)
+
+// see doc/key-range.md
+// the whole uint64 space is split into ranges of the same size
+// gateway that owns the range index can generate ids from it without asking anyone
+
+const (
+	RangeSize     uint64 = 1 << 30                // 1_073_741_824
+	MaxRangeIndex uint64 = uint64(1<<(64-30)) - 1 // [0:17_179_869_184)
+)
+
+var ErrIndexOutOfRange = errors.New("range index is out of range")
+
+// first and last id of the range
+func RangeForIndex(index uint64) (uint64, uint64, error) {
+	if index > MaxRangeIndex {
+		return 0, 0, fmt.Errorf("%w: %v > %v", ErrIndexOutOfRange, index, MaxRangeIndex)
+	}
+
+	return RangeSize * index, RangeSize*(index+1) - 1, nil
+}
+
+// Range that is given to a gateway
+type Range struct {
+	Index uint64
+	// first offset that is free to use
+	// not 0 when the range is reclaimed from an expired gateway
+	Offset uint64
+}
+
+// id for the offset of the range
+func (r Range) ID(offset uint64) (uint64, error) {
+	if offset >= RangeSize {
+		return 0, fmt.Errorf("offset %v is out of the range", offset)
+	}
+	first, _, err := RangeForIndex(r.Index)
+	if err != nil {
+		return 0, err
+	}
+	return first + offset, nil
+}
@@ -20,6 +20,7 @@ const (
 	Application Domain = "application"
 	Network     Domain = "network"
 	Election    Domain = "election"
+	Membership  Domain = "membership"
 )
 
 var (
@@ -28,6 +29,7 @@ var (
 		Application: true,
 		Network:     true,
 		Election:    true,
+		Membership:  true,
 	}
 )
 
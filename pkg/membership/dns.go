@@ -0,0 +1,150 @@
+package membership
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+// subset of *net.Resolver, tests use their own
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+type DNSConfig struct {
+	// headless service, for example maroon.default.svc.cluster.local
+	Service string
+	// named port of the service, SRV records are used
+	// empty - A records of the service and Port
+	PortName string
+	Port     int
+	// ip of the node itself, for example status.podIP from the downward API
+	// A records don't have pod names, so the node is skipped by it
+	SelfIP string
+	// between lookups
+	Interval time.Duration
+}
+
+// peers are the pods behind the headless service
+// SRV records of a StatefulSet's pods are <pod>.<service>, so the pod name is the member id
+// with A records it's the pod ip
+// DNS knows nothing about regions, all the members are in the region of the node
+// so it can't be used together with a per region quorum
+type DNSDiscovery struct {
+	resolver Resolver
+	cfg      DNSConfig
+	self     Member
+}
+
+func NewDNSDiscovery(resolver Resolver, cfg DNSConfig, self Member) *DNSDiscovery {
+	return &DNSDiscovery{
+		resolver: resolver,
+		cfg:      cfg,
+		self:     self,
+	}
+}
+
+// resolves the service every interval until ctx is done
+// onChange gets all the other members when they change, sorted by id
+// blocking
+func (d *DNSDiscovery) Run(ctx context.Context, onChange func([]Member)) error {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	var last []Member
+	known := false
+	for {
+		members, err := d.resolve(ctx)
+		if err != nil {
+			// pods come and go, the last known members stay
+			logger.Warningf(logger.Membership, "failed to resolve %v: %v", d.cfg.Service, err)
+		} else if !known || !slices.EqualFunc(last, members, sameMember) {
+			onChange(members)
+			last, known = members, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *DNSDiscovery) resolve(ctx context.Context) ([]Member, error) {
+	var members []Member
+	if d.cfg.PortName != "" {
+		_, records, err := d.resolver.LookupSRV(ctx, d.cfg.PortName, "tcp", d.cfg.Service)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			id, _, _ := strings.Cut(host, ".")
+			members = append(members, Member{ID: id, Addr: net.JoinHostPort(host, strconv.Itoa(int(srv.Port)))})
+		}
+	} else {
+		ips, err := d.resolver.LookupHost(ctx, d.cfg.Service)
+		if err != nil {
+			return nil, err
+		}
+		for _, ip := range ips {
+			if ip == d.cfg.SelfIP {
+				continue
+			}
+			members = append(members, Member{ID: ip, Addr: net.JoinHostPort(ip, strconv.Itoa(d.cfg.Port))})
+		}
+	}
+
+	res := make([]Member, 0, len(members))
+	for _, m := range members {
+		if m.ID == d.self.ID || m.Addr == d.self.Addr {
+			continue
+		}
+		m.Region = d.self.Region
+		res = append(res, m)
+	}
+	slices.SortFunc(res, func(a, b Member) int { return strings.Compare(a.ID, b.ID) })
+	// the same pod can be behind several records
+	return slices.CompactFunc(res, func(a, b Member) bool { return a.ID == b.ID }), nil
+}
+
+func sameMember(a, b Member) bool {
+	return a.ID == b.ID && a.Addr == b.Addr && a.Region == b.Region
+}
+
+// value of the pod label from the downward API labels file
+// lines are key="value"
+func LabelFromFile(path, key string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || k != key {
+			continue
+		}
+		value, err := strconv.Unquote(v)
+		if err != nil {
+			return "", fmt.Errorf("broken label %v: %w", key, err)
+		}
+		return value, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no label %v in %v", key, path)
+}
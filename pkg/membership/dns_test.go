@@ -0,0 +1,119 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type resolverMock struct {
+	mu    sync.Mutex
+	srv   []*net.SRV
+	hosts []string
+	err   error
+}
+
+func (r *resolverMock) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if service != "tcp" || proto != "tcp" || name != "maroon.default.svc.cluster.local" {
+		return "", nil, errors.New("no such host")
+	}
+	return "", r.srv, r.err
+}
+
+func (r *resolverMock) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if host != "maroon.default.svc.cluster.local" {
+		return nil, errors.New("no such host")
+	}
+	return r.hosts, r.err
+}
+
+func (r *resolverMock) set(err error, targets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+	r.srv = nil
+	for _, target := range targets {
+		r.srv = append(r.srv, &net.SRV{Target: target, Port: 8080})
+	}
+}
+
+func TestDNSDiscoveryFollowsRecords(t *testing.T) {
+	resolver := &resolverMock{}
+	resolver.set(nil,
+		"maroon-1.maroon.default.svc.cluster.local.",
+		"maroon-0.maroon.default.svc.cluster.local.",
+	)
+	d := NewDNSDiscovery(resolver, DNSConfig{
+		Service:  "maroon.default.svc.cluster.local",
+		PortName: "tcp",
+		Interval: 10 * time.Millisecond,
+	}, Member{ID: "maroon-0", Region: "eu"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changesCh := make(chan []Member, 100)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		require.NoError(t, d.Run(ctx, func(members []Member) { changesCh <- members }))
+	}()
+
+	maroon1 := Member{ID: "maroon-1", Addr: "maroon-1.maroon.default.svc.cluster.local:8080", Region: "eu"}
+	maroon2 := Member{ID: "maroon-2", Addr: "maroon-2.maroon.default.svc.cluster.local:8080", Region: "eu"}
+	require.Equal(t, []Member{maroon1}, <-changesCh)
+
+	// lookup failures keep the last members
+	resolver.set(errors.New("timeout"))
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, changesCh)
+
+	// scaled up
+	resolver.set(nil,
+		"maroon-2.maroon.default.svc.cluster.local.",
+		"maroon-0.maroon.default.svc.cluster.local.",
+		"maroon-1.maroon.default.svc.cluster.local.",
+	)
+	require.Equal(t, []Member{maroon1, maroon2}, <-changesCh)
+
+	cancel()
+	<-doneCh
+	require.Empty(t, changesCh)
+}
+
+func TestDNSDiscoverySkipsSelfIP(t *testing.T) {
+	resolver := &resolverMock{hosts: []string{"10.0.0.7", "10.0.0.5"}}
+	d := NewDNSDiscovery(resolver, DNSConfig{
+		Service:  "maroon.default.svc.cluster.local",
+		Port:     8080,
+		SelfIP:   "10.0.0.5",
+		Interval: time.Second,
+	}, Member{ID: "maroon-0", Addr: "maroon-0.maroon:8080"})
+
+	members, err := d.resolve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []Member{{ID: "10.0.0.7", Addr: "10.0.0.7:8080"}}, members)
+}
+
+func TestLabelFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels")
+	require.NoError(t, os.WriteFile(path, []byte(`app="maroon"
+topology.kubernetes.io/region="eu-west"
+`), 0o600))
+
+	region, err := LabelFromFile(path, "topology.kubernetes.io/region")
+	require.NoError(t, err)
+	require.Equal(t, "eu-west", region)
+
+	_, err = LabelFromFile(path, "zone")
+	require.Error(t, err)
+}
@@ -0,0 +1,210 @@
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// etcd layout under the prefix:
+//   - <node id> - Member as json, attached to the node's lease
+//     so it disappears when the node is gone
+type ETCD interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Put(ctx context.Context, key, val string, opts ...clientv3.OpOption) (*clientv3.PutResponse, error)
+	Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	KeepAliveOnce(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseKeepAliveResponse, error)
+	Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error)
+}
+
+type Member struct {
+	ID string `json:"id"`
+	// host:port of the p2p service
+	Addr   string `json:"addr"`
+	Region string `json:"region,omitempty"`
+	// what the node can do, for example the protocol it runs
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+type Registry struct {
+	cli    ETCD
+	prefix string
+	self   Member
+	// seconds
+	ttl int64
+}
+
+func NewRegistry(cli ETCD, prefix string, self Member, ttl int64) *Registry {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &Registry{
+		cli:    cli,
+		prefix: prefix,
+		self:   self,
+		ttl:    ttl,
+	}
+}
+
+// how long Run waits before it tries etcd again
+const (
+	minRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff = 5 * time.Second
+)
+
+// registers the node and keeps it registered until ctx is done
+// onChange gets all the other members every time the membership changes, sorted by id
+// etcd errors are retried, it only returns when ctx is done
+// blocking
+func (r *Registry) Run(ctx context.Context, onChange func([]Member)) error {
+	var lease clientv3.LeaseID
+	if !retry(ctx, func() error {
+		var err error
+		lease, err = r.register(ctx)
+		return err
+	}) {
+		return nil
+	}
+	defer func() {
+		// the others don't have to wait for the lease to expire
+		if _, err := r.cli.Revoke(context.Background(), lease); err != nil {
+			logger.Warningf(logger.Membership, "failed to revoke membership lease: %v", err)
+		}
+	}()
+
+	keepAlive := time.NewTicker(time.Duration(r.ttl) * time.Second / 3)
+	defer keepAlive.Stop()
+
+	for {
+		var members map[string]Member
+		var rev int64
+		if !retry(ctx, func() error {
+			var err error
+			members, rev, err = r.list(ctx)
+			return err
+		}) {
+			return nil
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		watchCh := r.cli.Watch(watchCtx, r.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		onChange(r.others(members))
+
+	watching:
+		for {
+			select {
+			case <-ctx.Done():
+				cancel()
+				return nil
+			case <-keepAlive.C:
+				if _, err := r.cli.KeepAliveOnce(ctx, lease); err != nil {
+					// lease could've expired during a network partition, so the key is gone
+					logger.Warningf(logger.Membership, "failed to keep membership lease alive, registering again: %v", err)
+					// the next tick tries again if it fails
+					if newLease, err := r.register(ctx); err != nil {
+						logger.Warningf(logger.Membership, "%v", err)
+					} else {
+						lease = newLease
+					}
+				}
+			case resp, ok := <-watchCh:
+				if !ok || resp.Err() != nil {
+					// compacted or etcd is restarted, events could be lost
+					logger.Warningf(logger.Membership, "membership watch is broken, listing again: %v", resp.Err())
+					break watching
+				}
+				for _, ev := range resp.Events {
+					id := strings.TrimPrefix(string(ev.Kv.Key), r.prefix)
+					if ev.Type == clientv3.EventTypeDelete {
+						delete(members, id)
+						continue
+					}
+					var m Member
+					if err := json.Unmarshal(ev.Kv.Value, &m); err != nil {
+						logger.Errorf(logger.Membership, "broken member %v: %v", id, err)
+						continue
+					}
+					members[id] = m
+				}
+				onChange(r.others(members))
+			}
+		}
+		cancel()
+	}
+}
+
+// calls fn with backoff until it succeeds
+// false if ctx is done before that
+func retry(ctx context.Context, fn func() error) bool {
+	backoff := minRetryBackoff
+	for {
+		err := fn()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		logger.Warningf(logger.Membership, "%v, retry in %v", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+func (r *Registry) register(ctx context.Context) (clientv3.LeaseID, error) {
+	value, err := json.Marshal(r.self)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode member: %w", err)
+	}
+	lease, err := r.cli.Grant(ctx, r.ttl)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create membership lease: %w", err)
+	}
+	if _, err := r.cli.Put(ctx, r.prefix+r.self.ID, string(value), clientv3.WithLease(lease.ID)); err != nil {
+		return 0, fmt.Errorf("failed to register member: %w", err)
+	}
+	logger.Infof(logger.Membership, "registered as %v at %v", r.self.ID, r.self.Addr)
+	return lease.ID, nil
+}
+
+// key - node id
+func (r *Registry) list(ctx context.Context) (map[string]Member, int64, error) {
+	resp, err := r.cli.Get(ctx, r.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list members: %w", err)
+	}
+	members := make(map[string]Member, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		id := strings.TrimPrefix(string(kv.Key), r.prefix)
+		var m Member
+		if err := json.Unmarshal(kv.Value, &m); err != nil {
+			logger.Errorf(logger.Membership, "broken member %v: %v", id, err)
+			continue
+		}
+		members[id] = m
+	}
+	return members, resp.Header.Revision, nil
+}
+
+func (r *Registry) others(members map[string]Member) []Member {
+	res := make([]Member, 0, len(members))
+	for id, m := range members {
+		if id == r.self.ID {
+			continue
+		}
+		res = append(res, m)
+	}
+	slices.SortFunc(res, func(a, b Member) int { return strings.Compare(a.ID, b.ID) })
+	return res
+}
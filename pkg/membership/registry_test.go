@@ -0,0 +1,134 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akantsevoi/test-environment/internal/test/etcdmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const prefix = "/maroon/members"
+
+// runs the registry, the last seen membership goes to the channel
+func runMember(t *testing.T, etcd ETCD, self Member) (<-chan []Member, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	changesCh := make(chan []Member, 100)
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		err := NewRegistry(etcd, prefix, self, 1).Run(ctx, func(members []Member) {
+			changesCh <- members
+		})
+		require.NoError(t, err)
+	}()
+	return changesCh, func() {
+		cancel()
+		<-doneCh
+	}
+}
+
+func waitMembers(t *testing.T, changesCh <-chan []Member, expected []Member) {
+	t.Helper()
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case members := <-changesCh:
+			if len(members) == 0 && len(expected) == 0 || assert.ObjectsAreEqual(expected, members) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("membership never became %v", expected)
+		}
+	}
+}
+
+func TestMembersSeeEachOther(t *testing.T) {
+	etcd := etcdmock.New()
+	a := Member{ID: "maroon-0", Addr: "maroon-0.maroon:8080", Region: "eu", Capabilities: []string{"blocks"}}
+	b := Member{ID: "maroon-1", Addr: "maroon-1.maroon:8080", Region: "us"}
+
+	aCh, stopA := runMember(t, etcd, a)
+	defer stopA()
+	waitMembers(t, aCh, nil)
+
+	bCh, stopB := runMember(t, etcd, b)
+	waitMembers(t, aCh, []Member{b})
+	waitMembers(t, bCh, []Member{a})
+
+	// the node is stopped, its key is gone right away
+	stopB()
+	waitMembers(t, aCh, nil)
+}
+
+func TestMemberRegistersAgainAfterLeaseExpired(t *testing.T) {
+	etcd := etcdmock.New()
+	a := Member{ID: "maroon-0", Addr: "maroon-0.maroon:8080"}
+	b := Member{ID: "maroon-1", Addr: "maroon-1.maroon:8080"}
+
+	aCh, stopA := runMember(t, etcd, a)
+	defer stopA()
+	_, stopB := runMember(t, etcd, b)
+	defer stopB()
+	waitMembers(t, aCh, []Member{b})
+
+	resp, err := etcd.Get(context.Background(), prefix+"/"+b.ID)
+	require.NoError(t, err)
+	require.Len(t, resp.Kvs, 1)
+	etcd.ExpireLease(clientv3.LeaseID(resp.Kvs[0].Lease))
+
+	waitMembers(t, aCh, nil)
+	// the next keep alive fails
+	waitMembers(t, aCh, []Member{b})
+}
+
+// etcd that fails the first calls of Grant and Get
+type flakyETCD struct {
+	ETCD
+
+	mu       sync.Mutex
+	failures int
+}
+
+func (e *flakyETCD) fail() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failures == 0 {
+		return nil
+	}
+	e.failures--
+	return errors.New("etcd is not available")
+}
+
+func (e *flakyETCD) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	if err := e.fail(); err != nil {
+		return nil, err
+	}
+	return e.ETCD.Grant(ctx, ttl)
+}
+
+func (e *flakyETCD) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	if err := e.fail(); err != nil {
+		return nil, err
+	}
+	return e.ETCD.Get(ctx, key, opts...)
+}
+
+func TestRegistryRetriesETCDErrors(t *testing.T) {
+	etcd := etcdmock.New()
+	a := Member{ID: "maroon-0", Addr: "maroon-0.maroon:8080"}
+	b := Member{ID: "maroon-1", Addr: "maroon-1.maroon:8080"}
+
+	_, stopA := runMember(t, etcd, a)
+	defer stopA()
+	// Grant fails twice, then Get fails once
+	bCh, stopB := runMember(t, &flakyETCD{ETCD: etcd, failures: 3}, b)
+	defer stopB()
+	waitMembers(t, bCh, []Member{a})
+}
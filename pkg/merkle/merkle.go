@@ -0,0 +1,140 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Merkle tree over an ordered list of leaves.
+// Hashing follows RFC 6962 (certificate transparency):
+//   - leaf hash = sha256(0x00 || leaf)
+//   - node hash = sha256(0x01 || left || right)
+//   - a tree of n leaves is split at the largest power of two smaller than n
+//
+// so odd amount of leaves doesn't require duplicating the last one
+// and a leaf can't be passed off as an inner node.
+
+const (
+	leafPrefix = 0x00
+	nodePrefix = 0x01
+)
+
+var ErrIndexOutOfRange = errors.New("leaf index out of range")
+
+type Tree struct {
+	// hashed leaves, order matters
+	leaves [][]byte
+}
+
+// Proof that leaf with Index is included into the tree of Size leaves
+type Proof struct {
+	Index int
+	Size  int
+	// sibling hashes from the bottom to the top
+	Path [][]byte
+}
+
+func New(leaves [][]byte) *Tree {
+	hashed := make([][]byte, len(leaves))
+	for i, l := range leaves {
+		hashed[i] = LeafHash(l)
+	}
+	return &Tree{leaves: hashed}
+}
+
+func LeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+func nodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{nodePrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+func (t *Tree) Len() int {
+	return len(t.leaves)
+}
+
+// for the empty tree it's a hash of an empty string
+func (t *Tree) Root() []byte {
+	if len(t.leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	}
+	return subtreeRoot(t.leaves)
+}
+
+func (t *Tree) Proof(index int) (Proof, error) {
+	if index < 0 || index >= len(t.leaves) {
+		return Proof{}, fmt.Errorf("%w: %d of %d", ErrIndexOutOfRange, index, len(t.leaves))
+	}
+	return Proof{
+		Index: index,
+		Size:  len(t.leaves),
+		Path:  auditPath(index, t.leaves),
+	}, nil
+}
+
+// checks that leaf is a part of the tree with the given root
+func Verify(root, leaf []byte, proof Proof) bool {
+	if proof.Index < 0 || proof.Index >= proof.Size {
+		return false
+	}
+
+	// RFC 9162 2.1.3.2
+	fn, sn := proof.Index, proof.Size-1
+	r := LeafHash(leaf)
+	for _, p := range proof.Path {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+func subtreeRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return nodeHash(subtreeRoot(leaves[:k]), subtreeRoot(leaves[k:]))
+}
+
+func auditPath(index int, leaves [][]byte) [][]byte {
+	if len(leaves) <= 1 {
+		return nil
+	}
+	k := split(len(leaves))
+	if index < k {
+		return append(auditPath(index, leaves[:k]), subtreeRoot(leaves[k:]))
+	}
+	return append(auditPath(index-k, leaves[k:]), subtreeRoot(leaves[:k]))
+}
+
+// largest power of two smaller than n
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
@@ -0,0 +1,55 @@
+package merkle
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func leaves(n int) [][]byte {
+	res := make([][]byte, n)
+	for i := range res {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("op-%d", i)))
+		res[i] = sum[:]
+	}
+	return res
+}
+
+func TestProofsForAllSizes(t *testing.T) {
+	for size := 1; size <= 17; size++ {
+		ls := leaves(size)
+		tree := New(ls)
+		root := tree.Root()
+
+		for i := range ls {
+			proof, err := tree.Proof(i)
+			require.NoError(t, err)
+			require.True(t, Verify(root, ls[i], proof), "size %d index %d", size, i)
+
+			// wrong leaf
+			require.False(t, Verify(root, []byte("garbage"), proof), "size %d index %d", size, i)
+
+			// wrong position
+			if size > 1 {
+				moved := proof
+				moved.Index = (i + 1) % size
+				require.False(t, Verify(root, ls[i], moved), "size %d index %d", size, i)
+			}
+		}
+	}
+}
+
+func TestRootDependsOnOrder(t *testing.T) {
+	ls := leaves(3)
+	swapped := [][]byte{ls[1], ls[0], ls[2]}
+
+	require.NotEqual(t, New(ls).Root(), New(swapped).Root())
+	require.Equal(t, LeafHash(ls[0]), New(ls[:1]).Root())
+}
+
+func TestProofOutOfRange(t *testing.T) {
+	_, err := New(leaves(2)).Proof(2)
+	require.ErrorIs(t, err, ErrIndexOutOfRange)
+}
@@ -0,0 +1,49 @@
+package wal
+
+import "time"
+
+type SyncPolicy int
+
+const (
+	// fsync after every append, record is on disk when Append returns
+	SyncAlways SyncPolicy = iota
+	// fsync in the background, records of the last interval can be lost
+	SyncInterval
+	// leave it to the OS
+	SyncNever
+)
+
+const (
+	defaultSegmentSize  = 64 << 20
+	defaultSyncInterval = 100 * time.Millisecond
+)
+
+type options struct {
+	segmentSize  int64
+	sync         SyncPolicy
+	syncInterval time.Duration
+}
+
+type Option func(*options)
+
+// segment is rotated when the next record doesn't fit into it
+// a record bigger than the size gets a segment for itself
+func WithSegmentSize(size int64) Option {
+	return func(o *options) {
+		o.segmentSize = size
+	}
+}
+
+// by default every append is synced
+func WithSync(policy SyncPolicy) Option {
+	return func(o *options) {
+		o.sync = policy
+	}
+}
+
+// only for SyncInterval
+func WithSyncInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.syncInterval = interval
+	}
+}
@@ -0,0 +1,137 @@
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// record on disk:
+//
+//	| length uint32 | crc32 of data uint32 | data |
+const headerSize = 8
+
+const segmentExt = ".wal"
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// tail of the segment is not a complete record
+// happens when the process dies in the middle of a write
+var errTorn = errors.New("torn record")
+
+type segment struct {
+	// index of the first record in the segment
+	first uint64
+	path  string
+}
+
+func segmentName(first uint64) string {
+	return fmt.Sprintf("%020d%s", first, segmentExt)
+}
+
+// segments of the dir ordered by the first index
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad segment name %q", ErrCorrupt, name)
+		}
+		res = append(res, segment{first: first, path: filepath.Join(dir, name)})
+	}
+	slices.SortFunc(res, func(a, b segment) int {
+		switch {
+		case a.first < b.first:
+			return -1
+		case a.first > b.first:
+			return 1
+		}
+		return 0
+	})
+	return res, nil
+}
+
+func encodeRecord(data []byte) []byte {
+	rec := make([]byte, headerSize+len(data))
+	binary.LittleEndian.PutUint32(rec[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(rec[4:8], crc32.Checksum(data, crcTable))
+	copy(rec[headerSize:], data)
+	return rec
+}
+
+// reads records of the segment one by one
+// returns amount of valid records and their size in bytes
+// on errTorn the valid part is still returned, so the tail can be cut off
+func scanSegment(path string, fn func(data []byte) error) (uint64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	var count uint64
+	var valid int64
+	header := make([]byte, headerSize)
+	for {
+		_, err := io.ReadFull(r, header)
+		if err == io.EOF {
+			return count, valid, nil
+		}
+		if err != nil {
+			return count, valid, fmt.Errorf("%w at %d: %v", errTorn, valid, err)
+		}
+
+		length := int64(binary.LittleEndian.Uint32(header[0:4]))
+		if valid+headerSize+length > stat.Size() {
+			return count, valid, fmt.Errorf("%w at %d: record is longer than the file", errTorn, valid)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return count, valid, fmt.Errorf("%w at %d: %v", errTorn, valid, err)
+		}
+		if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+			return count, valid, fmt.Errorf("%w at %d: checksum mismatch", errTorn, valid)
+		}
+
+		if fn != nil {
+			if err := fn(data); err != nil {
+				return count, valid, err
+			}
+		}
+		count++
+		valid += headerSize + length
+	}
+}
+
+// makes file creation and removal durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
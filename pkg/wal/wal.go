@@ -0,0 +1,300 @@
+package wal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/akantsevoi/test-environment/pkg/logger"
+)
+
+var (
+	ErrCorrupt = errors.New("wal is corrupted")
+	ErrClosed  = errors.New("wal is closed")
+)
+
+// WAL is an append only log of records split into segment files
+// records get sequential indexes starting from 1
+//
+// on Open the torn tail of the last segment is cut off,
+// any other broken record is reported as ErrCorrupt
+type WAL struct {
+	mu sync.Mutex
+
+	dir  string
+	opts options
+
+	segments []segment
+	// last segment, opened for writing
+	file *os.File
+	size int64
+
+	lastIndex uint64
+	// there are appended records that are not synced yet
+	dirty bool
+	// after a failed write the tail is unknown, so the WAL refuses to write anymore
+	err    error
+	closed bool
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func Open(dir string, opts ...Option) (*WAL, error) {
+	o := options{
+		segmentSize:  defaultSegmentSize,
+		sync:         SyncAlways,
+		syncInterval: defaultSyncInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create wal dir: %w", err)
+	}
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &WAL{
+		dir:  dir,
+		opts: o,
+	}
+	if len(segments) == 0 {
+		if err := w.createSegment(1); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := w.recover(segments); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.sync == SyncInterval {
+		w.stopCh = make(chan struct{})
+		w.doneCh = make(chan struct{})
+		go w.syncLoop()
+	}
+	return w, nil
+}
+
+// checks all the segments and cuts off the torn tail of the last one
+func (w *WAL) recover(segments []segment) error {
+	next := segments[0].first
+	var size int64
+	for i, s := range segments {
+		if s.first != next {
+			return fmt.Errorf("%w: segment %s should start from %d", ErrCorrupt, filepath.Base(s.path), next)
+		}
+		count, valid, err := scanSegment(s.path, nil)
+		last := i == len(segments)-1
+		if err != nil {
+			if !last || !errors.Is(err, errTorn) {
+				return fmt.Errorf("%w: segment %s: %v", ErrCorrupt, filepath.Base(s.path), err)
+			}
+			logger.Warningf(logger.Application, "wal: cut off torn tail of %s: %v", filepath.Base(s.path), err)
+			if err := os.Truncate(s.path, valid); err != nil {
+				return fmt.Errorf("failed to cut off torn tail: %w", err)
+			}
+		}
+		next += count
+		size = valid
+	}
+
+	last := segments[len(segments)-1]
+	f, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.segments = segments
+	w.file = f
+	w.size = size
+	w.lastIndex = next - 1
+	return nil
+}
+
+// returns index of the record
+func (w *WAL) Append(data []byte) (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	rec := encodeRecord(data)
+	if w.size > 0 && w.size+int64(len(rec)) > w.opts.segmentSize {
+		if err := w.rotate(); err != nil {
+			w.err = fmt.Errorf("failed to rotate segment: %w", err)
+			return 0, w.err
+		}
+	}
+
+	if _, err := w.file.Write(rec); err != nil {
+		w.err = fmt.Errorf("failed to write record: %w", err)
+		return 0, w.err
+	}
+	w.size += int64(len(rec))
+	w.lastIndex++
+
+	switch w.opts.sync {
+	case SyncAlways:
+		if err := w.file.Sync(); err != nil {
+			w.err = fmt.Errorf("failed to sync: %w", err)
+			return 0, w.err
+		}
+	case SyncInterval:
+		w.dirty = true
+	}
+	return w.lastIndex, nil
+}
+
+// goes through all the records from the oldest one
+func (w *WAL) Replay(fn func(index uint64, data []byte) error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	for _, s := range w.segments {
+		index := s.first
+		_, _, err := scanSegment(s.path, func(data []byte) error {
+			if err := fn(index, data); err != nil {
+				return err
+			}
+			index++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removes segments where all the records are below index
+// the segment with index itself stays, so a few older records can survive
+func (w *WAL) TruncateFront(index uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	// the last segment is never removed, it's the one for writing
+	var removed int
+	for removed < len(w.segments)-1 && w.segments[removed+1].first <= index {
+		if err := os.Remove(w.segments[removed].path); err != nil {
+			return fmt.Errorf("failed to remove segment: %w", err)
+		}
+		removed++
+	}
+	if removed == 0 {
+		return nil
+	}
+	w.segments = w.segments[removed:]
+	return syncDir(w.dir)
+}
+
+func (w *WAL) LastIndex() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastIndex
+}
+
+func (w *WAL) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sync()
+}
+
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	if w.stopCh != nil {
+		close(w.stopCh)
+		<-w.doneCh
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return errors.Join(w.file.Sync(), w.file.Close())
+}
+
+// should be called under mu
+func (w *WAL) sync() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// should be called under mu
+func (w *WAL) rotate() error {
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return w.createSegment(w.lastIndex + 1)
+}
+
+// should be called under mu
+func (w *WAL) createSegment(first uint64) error {
+	s := segment{first: first, path: filepath.Join(w.dir, segmentName(first))}
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := syncDir(w.dir); err != nil {
+		f.Close()
+		return err
+	}
+	w.segments = append(w.segments, s)
+	w.file = f
+	w.size = 0
+	return nil
+}
+
+func (w *WAL) syncLoop() {
+	defer close(w.doneCh)
+	ticker := time.NewTicker(w.opts.syncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.dirty && w.err == nil && !w.closed {
+				if err := w.sync(); err != nil {
+					w.err = fmt.Errorf("failed to sync: %w", err)
+					logger.Errorf(logger.Application, "wal: %v", w.err)
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
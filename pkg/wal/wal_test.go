@@ -0,0 +1,161 @@
+package wal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, w *WAL) []string {
+	var res []string
+	var expected uint64 = 1
+	require.NoError(t, w.Replay(func(index uint64, data []byte) error {
+		require.Equal(t, expected, index)
+		expected++
+		res = append(res, string(data))
+		return nil
+	}))
+	return res
+}
+
+func appendN(t *testing.T, w *WAL, from, to int) []string {
+	var res []string
+	for i := from; i < to; i++ {
+		rec := fmt.Sprintf("record-%d", i)
+		_, err := w.Append([]byte(rec))
+		require.NoError(t, err)
+		res = append(res, rec)
+	}
+	return res
+}
+
+func TestAppendReplayWithRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+
+	expected := appendN(t, w, 0, 20)
+	require.Equal(t, uint64(20), w.LastIndex())
+	require.Equal(t, expected, readAll(t, w))
+	require.NoError(t, w.Close())
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+
+	w, err = Open(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, expected, readAll(t, w))
+
+	index, err := w.Append([]byte("after restart"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(21), index)
+}
+
+func TestRecoverTornWrite(t *testing.T) {
+	for name, tail := range map[string]func(rec []byte) []byte{
+		"partial header": func(rec []byte) []byte { return rec[:3] },
+		"partial data":   func(rec []byte) []byte { return rec[:len(rec)-2] },
+		"broken data": func(rec []byte) []byte {
+			rec[len(rec)-1] ^= 0xff
+			return rec
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			w, err := Open(dir)
+			require.NoError(t, err)
+			expected := appendN(t, w, 0, 3)
+			require.NoError(t, w.Close())
+
+			// process died in the middle of the write
+			path := filepath.Join(dir, segmentName(1))
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+			require.NoError(t, err)
+			_, err = f.Write(tail(encodeRecord([]byte("lost record"))))
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+
+			w, err = Open(dir)
+			require.NoError(t, err)
+			defer w.Close()
+			require.Equal(t, expected, readAll(t, w))
+
+			index, err := w.Append([]byte("next"))
+			require.NoError(t, err)
+			require.Equal(t, uint64(4), index)
+			require.Equal(t, append(expected, "next"), readAll(t, w))
+		})
+	}
+}
+
+func TestCorruptedOldSegment(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	appendN(t, w, 0, 20)
+	require.NoError(t, w.Close())
+
+	// only the tail of the last segment can be torn
+	path := filepath.Join(dir, segmentName(1))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[headerSize] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+
+	_, err = Open(dir, WithSegmentSize(64))
+	require.ErrorIs(t, err, ErrCorrupt)
+}
+
+func TestSyncInterval(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, WithSync(SyncInterval), WithSyncInterval(time.Millisecond))
+	require.NoError(t, err)
+	appendN(t, w, 0, 5)
+
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return !w.dirty
+	}, time.Second, time.Millisecond)
+	require.NoError(t, w.Close())
+
+	_, err = w.Append([]byte("closed"))
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestTruncateFront(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	records := appendN(t, w, 0, 20)
+
+	segments, err := listSegments(dir)
+	require.NoError(t, err)
+	keep := segments[len(segments)/2]
+	require.NoError(t, w.TruncateFront(keep.first+1))
+
+	var replayed []string
+	var first uint64
+	require.NoError(t, w.Replay(func(index uint64, data []byte) error {
+		if first == 0 {
+			first = index
+		}
+		replayed = append(replayed, string(data))
+		return nil
+	}))
+	require.Equal(t, keep.first, first)
+	require.Equal(t, records[keep.first-1:], replayed)
+	require.NoError(t, w.Close())
+
+	// survives restart
+	w, err = Open(dir, WithSegmentSize(64))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, uint64(20), w.LastIndex())
+}